@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"log"
 	"time"
+	_ "time/tzdata" // the browser has no zone database of its own
 
 	"github.com/nlandolfi/elos/web-client/components/app"
+	"github.com/nlandolfi/elos/web-client/components/calendar"
 	"github.com/spinsrv/browser"
 	"github.com/spinsrv/browser/js"
 	"github.com/spinsrv/browser/ui"
@@ -41,6 +43,7 @@ func main() {
 
 	// TODO: is this right? - NCL 2/1/22
 	s.Rewire()
+	s.CalendarState.Notify = notify
 
 	m := &browser.Mounter{
 		Document: js.DefaultBrowser.Document(),
@@ -49,11 +52,23 @@ func main() {
 
 	go browser.Dispatch(app.EventInitialize{})
 
+	// the calendar's reminders are given as the ticks come
+	go func() {
+		for range time.NewTicker(calendar.TickEvery).C {
+			browser.Dispatch(calendar.EventTick{})
+		}
+	}()
+
 	for e := range browser.Events {
 		// temporary hack
 		s.NotesState.PrototypeState.Selection = js.DefaultBrowser.Document().Selection()
 		s.Handle(e)
 
+		// most ticks give no reminders, and so need neither a mount nor a save
+		if _, ok := e.(calendar.EventTick); ok && !s.CalendarState.Reminded() {
+			continue
+		}
+
 		if err := m.Mount(app.View(&s)); err != nil {
 			panic(err)
 		}
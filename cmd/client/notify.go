@@ -0,0 +1,24 @@
+//go:build js
+
+package main
+
+import "syscall/js"
+
+// notify shows a notification of the browser, and is whether it could.
+// The first time, it asks for permission instead, and so the reminder is
+// shown in the app.
+func notify(title, message string) bool {
+	n := js.Global().Get("Notification")
+	if n.IsUndefined() {
+		return false
+	}
+
+	switch n.Get("permission").String() {
+	case "granted":
+		n.New(title, map[string]interface{}{"body": message})
+		return true
+	case "default":
+		n.Call("requestPermission")
+	}
+	return false
+}
@@ -1,12 +1,155 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/ics"
+	"github.com/nlandolfi/elos/web-client/components/notes/note"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+var (
+	addr   = flag.String("addr", ":8080", "address to listen on")
+	static = flag.String("static", "./static", "directory of static files to serve")
+
+	// the feed is off unless a calendar is given
+	feedRoot    = flag.String("feed-root", "", "directory of spin files, by citizen, for the calendar feed")
+	feedCitizen = flag.String("feed-citizen", "", "citizen of the calendar to serve at /calendar.ics")
+	feedPath    = flag.String("feed-path", "", "path of the calendar to serve at /calendar.ics")
+
+	// the notes are unpublished unless a directory of them is given
+	notesDir = flag.String("notes-dir", "", "directory of notes to publish as HTML pages under /notes/")
 )
 
 func main() {
-	if err := http.ListenAndServe(":8080", http.FileServer(http.Dir("./static"))); err != nil {
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(*static)))
+
+	if *feedCitizen != "" && *feedPath != "" {
+		f := &feed{path: filepath.Join(*feedRoot, *feedCitizen, filepath.FromSlash(*feedPath))}
+		mux.Handle("/calendar.ics", f)
+		log.Printf("serving %s at /calendar.ics", f.path)
+	}
+
+	if *notesDir != "" {
+		mux.Handle("/notes/", http.StripPrefix("/notes", &notes{dir: *notesDir}))
+		log.Printf("publishing the notes of %s at /notes/", *notesDir)
+	}
+
+	if err := http.ListenAndServe(*addr, mux); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// feed serves a calendar file, read fresh for each request, as a read-only
+// iCalendar feed.
+type feed struct {
+	path string // of the calendar file; its extras are next to it
+}
+
+func (f *feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "read only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	b, err := f.calendar()
+	if err != nil {
+		log.Printf("feed: %v", err)
+		http.Error(w, "calendar unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Write(b)
+}
+
+func (f *feed) calendar() ([]byte, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	es, err := cal.ParseEvents(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// the extras are missing until the calendar's first save with some
+	bs, err := ioutil.ReadFile(f.path + extra.Suffix)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	t, err := extra.Read(bytes.NewReader(bs), es)
+	if err != nil {
+		return nil, err
+	}
+	t.Identify(es) // for the UIDs
+
+	var b bytes.Buffer
+	if err := ics.Write(&b, es, t); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// notes serves the notes of a directory, read fresh for each request, as
+// HTML pages: /notes/a/b is the note in the file a/b of the directory.
+type notes struct {
+	dir string
+}
+
+func (ns *notes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "read only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// cleaned as if rooted, the path stays in the directory
+	p := filepath.Join(ns.dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	if fi, err := os.Stat(p); os.IsNotExist(err) || (err == nil && fi.IsDir()) {
+		http.NotFound(w, r)
+		return
+	}
+
+	b, err := ns.page(p)
+	if err != nil {
+		log.Printf("notes: %v", err)
+		http.Error(w, "note unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(b)
+}
+
+func (ns *notes) page(p string) ([]byte, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	n, err := note.Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", p, err)
+	}
+
+	var b bytes.Buffer
+	note.RenderHTML(&b, n)
+	return b.Bytes(), nil
+}
@@ -73,10 +73,7 @@ func (s *State) Handle(e browser.Event) {
 	case sidebar.EventItemHoverStart, sidebar.EventItemHoverEnd:
 		s.SidebarState.Handle(e)
 	case manager.EventOpenInEditor:
-		s.OpenInEditor(
-			ctzn.Name(s.CalendarState.ManagerState.Calendar.Citizen),
-			fs.Path(s.CalendarState.ManagerState.Calendar.Path),
-		)
+		s.OpenInEditor(ctzn.Name(v.Citizen), fs.Path(v.Path))
 	default:
 	}
 
@@ -0,0 +1,168 @@
+package agenda
+
+import (
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/ecard"
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/ltr"
+	"github.com/nlandolfi/spin/apps/cal"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+// DefaultDays is how many days the agenda lists at first, and how many
+// more it lists each time it is asked for later ones.
+const DefaultDays = 14
+
+type State struct {
+	Theme      *ui.Theme        `json:"-"`
+	Time       *time.Time       `json:"-"`
+	EventItems []*cal.EventItem `json:"-"` // this is a pointer
+	Extras     extra.Table      `json:"-"`
+
+	// Days is how many days, from the day of Time, the agenda lists.
+	Days int
+
+	// Color, if set, is the color of an event.
+	Color func(e *cal.EventItem) string `json:"-"`
+}
+
+func (s *State) Handle(e browser.Event) {
+	switch e := e.(type) {
+	case EventDecrementWeek:
+		*s.Time = s.Time.AddDate(0, 0, -7)
+	case EventSetTime:
+		*s.Time = e.Time
+		s.Days = DefaultDays
+	case EventIncrementWeek:
+		*s.Time = s.Time.AddDate(0, 0, 7)
+	case EventMore:
+		s.Days += DefaultDays
+	}
+}
+
+type EventDecrementWeek struct{}
+type EventSetTime struct{ time.Time }
+type EventIncrementWeek struct{}
+
+// EventMore asks for the days after those listed.
+type EventMore struct{}
+
+// EventEventClick is a click on the occurrence of an event At.
+type EventEventClick struct {
+	*cal.EventItem
+	At time.Time
+}
+
+func (s *State) SetTheme(t *ui.Theme) {
+	s.Theme = t
+}
+
+// occurrences is the occurrences of es in the days from the day of from,
+// by the day they start on, in order of their start. An occurrence which
+// started before from is on its first day.
+func occurrences(from time.Time, days int, es []*cal.EventItem, t extra.Table) [][]extra.Occurrence {
+	from = extra.Midnight(from)
+	byDay := make([][]extra.Occurrence, days)
+	for _, o := range t.Occurrences(es, from, from.AddDate(0, 0, days)) {
+		i := extra.Days(from, o.Start)
+		if i < 0 {
+			i = 0
+		}
+		byDay[i] = append(byDay[i], o)
+	}
+	return byDay
+}
+
+// heading is the heading of day, relative to now where it's near.
+func heading(day, now time.Time) string {
+	switch extra.Days(now, day) {
+	case -1:
+		return "Yesterday"
+	case 0:
+		return "Today"
+	case 1:
+		return "Tomorrow"
+	}
+	if day.Year() == now.Year() {
+		return day.Format("Monday January 2")
+	}
+	return day.Format("Monday January 2, 2006")
+}
+
+func View(s *State) *browser.Node {
+	days := s.Days
+	if days <= 0 {
+		days = DefaultDays
+	}
+	from := extra.Midnight(*s.Time)
+	now := time.Now().In(from.Location()) // todo pull current time from state?
+
+	var groups []*browser.Node
+	for d, os := range occurrences(from, days, s.EventItems, s.Extras) {
+		if len(os) == 0 {
+			continue
+		}
+		groups = append(groups, dayView(s, from.AddDate(0, 0, d), now, os))
+	}
+
+	return ui.VStack(
+		ui.HStack(
+			s.Theme.Text(from.Format("From January 2, 2006")).
+				FontSizeEM(1.5).
+				MarginLeftPX(10),
+			ui.Spacer(),
+			ltr.View(&ltr.State{
+				Theme:        s.Theme,
+				OnClickPrev:  browser.Dispatcher(EventDecrementWeek{}),
+				OnClickToday: browser.Dispatcher(EventSetTime{time.Now()}),
+				OnClickNext:  browser.Dispatcher(EventIncrementWeek{}),
+			}),
+		).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap),
+		ui.If(len(groups) > 0,
+			func() *browser.Node {
+				return ui.VStack(groups...)
+			},
+			func() *browser.Node {
+				return s.Theme.Textf("No events in the %d days from %s...", days, from.Format("2 Jan"))
+			},
+		),
+		ui.HStack(
+			s.Theme.Textf("Until %s", from.AddDate(0, 0, days-1).Format("2 Jan 2006")).
+				Color("lightgray"), // TODO: pull color from theme
+			ui.Spacer(),
+			s.Theme.Button("Later").OnClickDispatch(EventMore{}),
+		).AlignItemsCenter().MarginTopPX(10),
+	)
+}
+
+// color is the color of e, if there is one.
+func color(s *State, e *cal.EventItem) string {
+	if s.Color == nil {
+		return ""
+	}
+	return s.Color(e)
+}
+
+func dayView(s *State, day, now time.Time, os []extra.Occurrence) *browser.Node {
+	cards := make([]*browser.Node, len(os))
+	for i, o := range os {
+		cards[i] = ecard.View(&ecard.State{
+			Theme:            s.Theme,
+			Item:             o.EventItem,
+			Extras:           s.Extras,
+			RecurrenceNumber: o.N,
+			At:               o.Start,
+			Color:            color(s, o.EventItem),
+		}).OnClickCached(o.ID+o.Start.String(), browser.Dispatcher(EventEventClick{o.EventItem, o.Start}))
+	}
+
+	return ui.VStack(
+		s.Theme.Text(heading(day, now)).
+			FontSizeEM(1.2).
+			MarginTopPX(10).
+			MarginBottomPX(5),
+		ui.VStack(cards...),
+	)
+}
@@ -0,0 +1,85 @@
+package agenda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func TestOccurrences(t *testing.T) {
+	weekly := &cal.EventItem{
+		Name:          "Standup",
+		Time:          time.Date(2022, 3, 1, 9, 0, 0, 0, time.UTC),
+		HourSpecified: true,
+		Recurs:        true,
+		Frequency:     "weekly",
+	}
+	trip := &cal.EventItem{
+		Name: "Trip",
+		Time: time.Date(2022, 3, 13, 0, 0, 0, 0, time.UTC),
+	}
+	early := &cal.EventItem{
+		Name:          "Breakfast",
+		Time:          time.Date(2022, 3, 15, 8, 0, 0, 0, time.UTC),
+		HourSpecified: true,
+	}
+	tb := extra.Table{trip: {End: time.Date(2022, 3, 16, 0, 0, 0, 0, time.UTC)}}
+
+	from := time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC)
+	byDay := occurrences(from, 7, []*cal.EventItem{weekly, trip, early}, tb)
+
+	if len(byDay) != 7 {
+		t.Fatalf("got %d days, want 7", len(byDay))
+	}
+
+	// the trip started the day before, so is on the first day
+	if got := names(byDay[0]); got != "Trip" {
+		t.Errorf("day 0: got %q, want %q", got, "Trip")
+	}
+	if got := names(byDay[1]); got != "Breakfast,Standup" {
+		t.Errorf("day 1: got %q, want %q", got, "Breakfast,Standup")
+	}
+	if got := byDay[1][1].N; got != 3 {
+		t.Errorf("standup: got occurrence %d, want 3", got)
+	}
+	if got := byDay[1][0].N; got != 0 {
+		t.Errorf("breakfast: got occurrence %d, want 0", got)
+	}
+	for d := 2; d < 7; d++ {
+		if len(byDay[d]) != 0 {
+			t.Errorf("day %d: got %q, want none", d, names(byDay[d]))
+		}
+	}
+}
+
+func names(os []extra.Occurrence) string {
+	var s string
+	for i, o := range os {
+		if i > 0 {
+			s += ","
+		}
+		s += o.Name
+	}
+	return s
+}
+
+func TestHeading(t *testing.T) {
+	now := time.Date(2022, 3, 14, 18, 0, 0, 0, time.UTC)
+	cases := []struct {
+		day  time.Time
+		want string
+	}{
+		{now.AddDate(0, 0, -1), "Yesterday"},
+		{now, "Today"},
+		{now.AddDate(0, 0, 1), "Tomorrow"},
+		{now.AddDate(0, 0, 2), "Wednesday March 16"},
+		{now.AddDate(1, 0, 0), "Tuesday March 14, 2023"},
+	}
+	for _, c := range cases {
+		if got := heading(c.day, now); got != c.want {
+			t.Errorf("heading(%v): got %q, want %q", c.day, got, c.want)
+		}
+	}
+}
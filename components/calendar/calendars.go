@@ -0,0 +1,253 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/editor"
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/manager"
+	"github.com/nlandolfi/spin/apps/cal"
+	feditor "github.com/nlandolfi/spin/web/elos/components/editor"
+	"github.com/spinsrv/browser"
+)
+
+// Calendar is a calendar of the manager, as loaded.
+type Calendar struct {
+	manager.CalendarReference
+
+	File feditor.File
+
+	// ExtrasFile is next to the File, and keeps what the events of the
+	// calendar have no field for.
+	ExtrasFile feditor.File
+
+	// eventsErr and extrasErr are why the File and the ExtrasFile couldn't
+	// be read, if they couldn't. Neither is then written over, so as not
+	// to lose what they keep: the File holds events which weren't read, and
+	// the ExtrasFile the extras of the events which were.
+	eventsErr, extrasErr error
+}
+
+// defaultColor is the color of events of no calendar.
+const defaultColor = "lightgray"
+
+func (s *State) reloadEvents() {
+	s.InspectorVisible = false
+	s.InspectedEvent = nil
+	s.EditorState.Event = nil
+
+	s.ManagerState.Migrate()
+	s.Calendars = nil
+	for _, r := range s.ManagerState.Calendars {
+		c := &Calendar{CalendarReference: r}
+
+		c.File.PrivateKey = s.PrivateKey
+		c.File.Citizen = r.Citizen
+		c.File.Path = r.Path
+		c.File.Reload()
+
+		c.ExtrasFile.PrivateKey = s.PrivateKey
+		c.ExtrasFile.Citizen = r.Citizen
+		c.ExtrasFile.Path = r.Path + extra.Suffix
+		c.ExtrasFile.Reload() // the file is missing until the first save
+
+		s.Calendars = append(s.Calendars, c)
+	}
+
+	s.reloadEventItems()
+}
+
+// reloadEventItems reads the events of the Calendars from their files.
+func (s *State) reloadEventItems() {
+	s.Status = strings.Join(s.read(), "; ")
+
+	// the event inspected is one of those just read, if it wasn't deleted
+	if s.InspectedEvent != nil {
+		s.InspectedEvent = s.event(s.InspectedEvent.ID)
+		s.InspectorVisible = s.InspectorVisible && s.InspectedEvent != nil
+	}
+	s.Rewire(s.Theme, s.PrivateKey)
+}
+
+// read reads the events of the Calendars, and their extras, from the text
+// of their files, and is the problems it had.
+func (s *State) read() (errs []string) {
+	s.loaded = true
+	s.EventItems = nil
+	s.Extras = make(extra.Table)
+	s.owners = make(map[*cal.EventItem]*Calendar)
+
+	for _, c := range s.Calendars {
+		es, err := cal.ParseEvents(bytes.NewBufferString(c.File.Text))
+		c.eventsErr = err
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+			continue
+		}
+		t, err := extra.Read(bytes.NewBufferString(c.ExtrasFile.Text), es)
+		c.extrasErr = err
+		if err != nil {
+			// the events are still shown, without their extras
+			errs = append(errs, fmt.Sprintf("%s: %v", c.ExtrasFile.Path, err))
+			t = make(extra.Table)
+		}
+
+		for _, e := range es {
+			if i, ok := t[e]; ok {
+				s.Extras[e] = i
+			}
+			s.owners[e] = c
+		}
+		s.EventItems = append(s.EventItems, es...)
+	}
+	// the IDs are across calendars, for the overrides of events
+	s.Extras.Identify(s.EventItems)
+	if err := s.Extras.CheckZones(s.EventItems); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+// save writes the events of each calendar which may be written to its
+// files, and saves those which changed.
+func (s *State) save() {
+	log.Print("writing events")
+	s.Extras.Identify(s.EventItems) // events added since the load have none
+
+	var errs []string
+	for _, c := range s.Calendars {
+		if c.ReadOnly {
+			continue
+		}
+		// the calendar owns none of the events of its file, which would be
+		// lost were it written
+		if c.eventsErr != nil {
+			errs = append(errs, fmt.Sprintf("%s: not saved, as it couldn't be read: %v", c.Path, c.eventsErr))
+			continue
+		}
+
+		events, extras, err := s.write(c)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.Path, err))
+			continue
+		}
+		if c.File.Text != events {
+			log.Printf("and saving %s", c.Path)
+			c.File.Text = events
+			c.File.Save()
+		}
+
+		// the extras are saved after the calendar, and not with it; should
+		// they not be, extra.Read still matches them to the events which
+		// kept their name and time, and the others are given new IDs
+		if c.extrasErr != nil {
+			errs = append(errs, fmt.Sprintf("%s: not saved, as it couldn't be read: %v", c.ExtrasFile.Path, c.extrasErr))
+			continue
+		}
+		if c.ExtrasFile.Text != extras {
+			c.ExtrasFile.Text = extras
+			c.ExtrasFile.Save()
+		}
+	}
+
+	s.reloadEventItems()
+	if len(errs) > 0 {
+		s.Status = strings.Join(errs, "; ")
+	}
+	go browser.Dispatch(editor.EventSaved{})
+}
+
+// write is the text of the files of c, of the events it owns.
+func (s *State) write(c *Calendar) (events, extras string, err error) {
+	var es []*cal.EventItem
+	for _, e := range s.EventItems {
+		if s.owners[e] == c {
+			es = append(es, e)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := cal.WriteEvents(&b, es); err != nil {
+		return "", "", err
+	}
+	events = b.String()
+
+	b.Reset()
+	if err := extra.Write(&b, es, s.Extras); err != nil {
+		return "", "", err
+	}
+	return events, b.String(), nil
+}
+
+// syncCalendars brings the Calendars up to date with the colors and flags
+// of those of the manager, which are in the same order until a reload.
+func (s *State) syncCalendars() {
+	if len(s.Calendars) != len(s.ManagerState.Calendars) {
+		return
+	}
+	for i, c := range s.Calendars {
+		c.CalendarReference = s.ManagerState.Calendars[i]
+	}
+}
+
+// calendar is the calendar new events go in by default: the first which
+// may be written to.
+func (s *State) calendar() *Calendar {
+	for _, c := range s.Calendars {
+		if !c.ReadOnly {
+			return c
+		}
+	}
+	return nil
+}
+
+// writable is whether e is of a calendar which may be written to.
+func (s *State) writable(e *cal.EventItem) bool {
+	c := s.owners[e]
+	return c != nil && !c.ReadOnly
+}
+
+// shown is the events of the calendars which aren't hidden.
+func (s *State) shown() []*cal.EventItem {
+	var es []*cal.EventItem
+	for _, e := range s.EventItems {
+		if c := s.owners[e]; c == nil || !c.Hidden {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+// color is the color of e: that of its first tag with one, or else that of
+// its calendar.
+func (s *State) color(e *cal.EventItem) string {
+	for _, tag := range s.Extras.Tags(e) {
+		if c := s.TagColors[tag]; c != "" {
+			return c
+		}
+	}
+	if c := s.owners[e]; c != nil && c.Color != "" {
+		return c.Color
+	}
+	return defaultColor
+}
+
+// tags is the tags of the events shown, in order.
+func (s *State) tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, e := range s.shown() {
+		for _, tag := range s.Extras.Tags(e) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,187 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/manager"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func at(d, h int) time.Time {
+	return time.Date(2022, 3, d, h, 0, 0, 0, time.UTC)
+}
+
+// calendarOf is a calendar at path whose files hold es, and their extras
+// in t.
+func calendarOf(t *testing.T, path string, es []*cal.EventItem, tb extra.Table) *Calendar {
+	c := &Calendar{CalendarReference: manager.CalendarReference{Path: path}}
+	c.ExtrasFile.Path = path + extra.Suffix
+
+	var b bytes.Buffer
+	if err := cal.WriteEvents(&b, es); err != nil {
+		t.Fatal(err)
+	}
+	c.File.Text = b.String()
+
+	b.Reset()
+	if err := extra.Write(&b, es, tb); err != nil {
+		t.Fatal(err)
+	}
+	c.ExtrasFile.Text = b.String()
+	return c
+}
+
+// named is the event of s named name.
+func named(s *State, name string) *cal.EventItem {
+	for _, e := range s.EventItems {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+func TestRead(t *testing.T) {
+	standup := &cal.EventItem{Name: "standup", Time: at(1, 9), HourSpecified: true}
+	dinner := &cal.EventItem{Name: "dinner", Time: at(1, 19), HourSpecified: true}
+	gym := &cal.EventItem{Name: "gym", Time: at(2, 18), HourSpecified: true}
+
+	work := calendarOf(t, "work", []*cal.EventItem{standup}, extra.Table{standup: {ID: "s", Tags: []string{"team"}}})
+	home := calendarOf(t, "home", []*cal.EventItem{dinner, gym}, extra.Table{dinner: {ID: "d"}})
+	broken := calendarOf(t, "broken", []*cal.EventItem{{Name: "lost", Time: at(3, 9)}}, nil)
+	broken.ExtrasFile.Text = "{not json"
+
+	s := &State{Calendars: []*Calendar{work, home, broken}}
+	errs := s.read()
+
+	if len(s.EventItems) != 4 {
+		t.Fatalf("read %d events, want the 4 of the calendars", len(s.EventItems))
+	}
+	for name, c := range map[string]*Calendar{"standup": work, "dinner": home, "gym": home, "lost": broken} {
+		e := named(s, name)
+		if e == nil || s.owners[e] != c {
+			t.Errorf("%s is of %v, want %s", name, s.owners[e], c.Path)
+		}
+	}
+
+	if e := named(s, "standup"); e.ID != "s" || !s.Extras.HasTag(e, "team") {
+		t.Errorf("standup has ID %q and tags %v, want those of its extras", e.ID, s.Extras.Tags(e))
+	}
+	if e := named(s, "gym"); e.ID == "" || e.ID == "s" || e.ID == "d" {
+		t.Errorf("gym has ID %q, want a new one", e.ID)
+	}
+
+	// the events of a calendar whose extras can't be read are still there
+	if len(errs) != 1 || broken.extrasErr == nil {
+		t.Errorf("errors = %v, want one of the broken extras", errs)
+	}
+	if e := named(s, "lost"); e == nil || e.ID == "" {
+		t.Error("the event of the broken calendar was not read, or not given an ID")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	standup := &cal.EventItem{Name: "standup", Time: at(1, 9), HourSpecified: true}
+	dinner := &cal.EventItem{Name: "dinner", Time: at(1, 19), HourSpecified: true}
+	work := calendarOf(t, "work", []*cal.EventItem{standup}, nil)
+	home := calendarOf(t, "home", []*cal.EventItem{dinner}, nil)
+
+	s := &State{Calendars: []*Calendar{work, home}}
+	s.read()
+
+	// a new event of the calendar goes in its file, and only in its
+	review := &cal.EventItem{Name: "review", Time: at(4, 14), HourSpecified: true}
+	s.owners[review] = work
+	s.add(review)
+	s.Extras.Of(review).Tags = []string{"team"}
+
+	events, extras, err := s.write(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es, err := cal.ParseEvents(bytes.NewBufferString(events))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 2 || es[0].Name != "standup" || es[1].Name != "review" {
+		t.Fatalf("wrote %v to work, want standup and review", es)
+	}
+	tb, err := extra.Read(bytes.NewBufferString(extras), es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tb.HasTag(es[1], "team") || tb[es[1]].ID != review.ID {
+		t.Errorf("wrote extras %+v for review, want its ID and tag", tb[es[1]])
+	}
+
+	events, _, err = s.write(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es, _ := cal.ParseEvents(bytes.NewBufferString(events)); len(es) != 1 || es[0].Name != "dinner" {
+		t.Errorf("wrote %v to home, want dinner", es)
+	}
+}
+
+func TestSaveUnread(t *testing.T) {
+	standup := &cal.EventItem{Name: "standup", Time: at(1, 9), HourSpecified: true}
+	work := calendarOf(t, "work", []*cal.EventItem{standup}, nil)
+	home := calendarOf(t, "home", nil, nil)
+	home.File.Text = "{not json"
+	home.ExtrasFile.Text = "[]\n"
+
+	s := &State{Calendars: []*Calendar{work, home}}
+	if errs := s.read(); len(errs) != 1 || home.eventsErr == nil {
+		t.Fatalf("errors = %v, want one of the broken calendar", errs)
+	}
+
+	// an edit to another calendar leaves the one which couldn't be read
+	s.Extras.Of(named(s, "standup")).Tags = []string{"team"}
+	s.save()
+
+	if home.File.Text != "{not json" || home.ExtrasFile.Text != "[]\n" {
+		t.Errorf("saved %q and %q over the calendar which couldn't be read", home.File.Text, home.ExtrasFile.Text)
+	}
+	if !strings.Contains(s.Status, "home") {
+		t.Errorf("status = %q, want it to say home wasn't saved", s.Status)
+	}
+	if tb, err := extra.Read(bytes.NewBufferString(work.ExtrasFile.Text), s.EventItems); err != nil || !tb.HasTag(named(s, "standup"), "team") {
+		t.Errorf("the edit to work wasn't saved: %q", work.ExtrasFile.Text)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	dinner := &cal.EventItem{Name: "dinner", Time: at(1, 19), HourSpecified: true}
+	home := calendarOf(t, "home", []*cal.EventItem{dinner}, nil)
+	home.ReadOnly = true
+
+	s := &State{Calendars: []*Calendar{home}}
+	s.read()
+	e := named(s, "dinner")
+
+	if s.writable(e) {
+		t.Error("an event of a read only calendar is writable")
+	}
+	if s.calendar() != nil {
+		t.Error("new events go in a read only calendar")
+	}
+
+	s.moveEvent(e, e.Time, 1)
+	if !e.Time.Equal(at(1, 19)) || s.Status == "" {
+		t.Errorf("moved an event of a read only calendar to %v", e.Time)
+	}
+
+	s.EditorState.Original, s.EditorState.Event = e, &cal.EventItem{Name: "supper", Time: e.Time}
+	s.saveEdit()
+	if e.Name != "dinner" || s.EditorState.Status == "" {
+		t.Error("saved an edit to an event of a read only calendar")
+	}
+	s.deleteEdit()
+	if named(s, "dinner") == nil {
+		t.Error("deleted an event of a read only calendar")
+	}
+}
@@ -1,8 +1,10 @@
 package day
 
 import (
+	"strings"
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/elos/web-client/components/calendar/ltr"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/spinsrv/browser"
@@ -14,8 +16,11 @@ type State struct {
 	Theme      *ui.Theme        `json:"-"`
 	Time       *time.Time       `json:"-"`
 	EventItems []*cal.EventItem `json:"-"` // this is a pointer
+	Extras     extra.Table      `json:"-"`
 
-	DispatchEditEvent func(*cal.EventItem) `json:"-"`
+	DispatchEditEvent func(e *cal.EventItem, at time.Time) `json:"-"` // at is the occurrence
+	// Color, if set, is the color of an event.
+	Color func(e *cal.EventItem) string `json:"-"`
 
 	hoveredEventID string
 }
@@ -48,25 +53,20 @@ func (s *State) SetTheme(t *ui.Theme) {
 }
 
 func View(s *State) *browser.Node {
-	var es cal.EventItems
+	day := extra.Midnight(*s.Time)
+	os := s.Extras.Occurrences(s.EventItems, day, day.AddDate(0, 0, 1))
 
-	for _, e := range s.EventItems {
-		if e.ShouldDisplayOnDay(*s.Time) {
-			es = append(es, e)
-		}
-	}
+	cards := make([]*browser.Node, len(os))
 
-	cards := make([]*browser.Node, len(es))
-
-	for i, e := range es {
-		cards[i] = dayCard(s, e)
+	for i, o := range os {
+		cards[i] = dayCard(s, o)
 	}
 
 	return ui.VStack(
 		ui.HStack(
 			s.Theme.Text(s.Time.Format("Monday January 2, 2006")).
 				FontSizeEM(1.5),
-			ui.OnlyIf(cal.SameDay(*s.Time, time.Now()), // todo pull current time from state?
+			ui.OnlyIf(cal.SameDay(*s.Time, time.Now().In(s.Time.Location())), // todo pull current time from state?
 				func() *browser.Node {
 					return s.Theme.Text(s.Time.Format("(today)")).
 						FontSizeEM(1).
@@ -93,17 +93,26 @@ func View(s *State) *browser.Node {
 	)
 }
 
-func dayCard(s *State, item *cal.EventItem) *browser.Node {
+func dayCard(s *State, o extra.Occurrence) *browser.Node {
+	item := o.EventItem
 	return s.Theme.Card(
 		ui.HStack(
 			s.Theme.Text(item.Name).
 				FontSize(browser.Size{Value: 1.2, Unit: browser.UnitEM}),
 			ui.Spacer(),
-			s.Theme.Text(item.Time.Format("2 Jan 2006")),
+			ui.If(item.HourSpecified,
+				func() *browser.Node { return s.Theme.Text(o.Start.Format("2 Jan 2006 @ 3:04 PM")) },
+				func() *browser.Node { return s.Theme.Text(o.Start.Format("2 Jan 2006")) },
+			),
+		),
+		ui.OnlyIf(s.Extras.When(item) != "",
+			func() *browser.Node {
+				return s.Theme.Text(s.Extras.When(item))
+			},
 		),
-		ui.OnlyIf(item.HourSpecified,
+		ui.OnlyIf(len(s.Extras.Tags(item)) > 0,
 			func() *browser.Node {
-				return s.Theme.Text(item.Time.Format("3:04 PM"))
+				return s.Theme.Text(strings.Join(s.Extras.Tags(item), ", ")).Color("gray") // TODO: pull color from theme
 			},
 		),
 		ui.If(len(item.Details) == 0,
@@ -116,7 +125,7 @@ func dayCard(s *State, item *cal.EventItem) *browser.Node {
 		),
 		ui.If(item.Recurs,
 			func() *browser.Node {
-				return s.Theme.Text(item.RecurString())
+				return s.Theme.Textf("%s (occurrence %d)", item.RecurString(), o.N)
 			},
 			func() *browser.Node {
 				return s.Theme.Text("No recurrence...").Color("lightgray").FontSizeEM(0.5) // TODO: pull color from theme
@@ -126,9 +135,16 @@ func dayCard(s *State, item *cal.EventItem) *browser.Node {
 			ui.Spacer(),
 			s.Theme.Button("Edit").OnClick(func(_ dom.Event) {
 				if s.DispatchEditEvent != nil {
-					go s.DispatchEditEvent(item)
+					go s.DispatchEditEvent(item, o.Start)
 				}
 			}),
 		),
-	).PaddingPX(10)
+	).PaddingPX(10).
+		OnlyIf(s.Color != nil, func(n *browser.Node) *browser.Node {
+			return n.BorderLeft(browser.Border{
+				Color: s.Color(item),
+				Width: browser.Size{Value: 4, Unit: browser.UnitPX},
+				Type:  browser.BorderSolid,
+			})
+		})
 }
@@ -1,41 +1,81 @@
 package ecard
 
 import (
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/spin/apps/cal"
-	"github.com/nlandolfi/spin/web/browser"
-	"github.com/nlandolfi/spin/web/browser/ui"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
 )
 
 type State struct {
-	Theme *ui.Theme
-	Item  *cal.EventItem
-	// below is not implemented
-	RecurrenceNumber int // if set, the display will make it look like this events time is this reccurence
+	Theme  *ui.Theme
+	Item   *cal.EventItem
+	Extras extra.Table
+
+	// RecurrenceNumber, if set, is which occurrence of Item, counting from
+	// one, the card is of; At is when it starts, and the card shows it
+	// in place of the time of the first.
+	RecurrenceNumber int
+	At               time.Time
+
+	// Color, if set, is the color of the event.
+	Color string
 }
 
 func View(s *State) *browser.Node {
 	item := *s.Item
 
+	start := item.Time
+	if s.RecurrenceNumber > 0 {
+		start = s.At
+	}
+
 	return s.Theme.Card(
-		ui.VStack(
-			ui.HStack(
-				s.Theme.Text(item.Name),
-				ui.If(
-					!item.HourSpecified,
-					s.Theme.Text(item.DateString()),
-					s.Theme.Textf("%s @ %s", item.DateString(), item.TimeString()),
-				),
+		ui.HStack(
+			s.Theme.Text(item.Name),
+			ui.Spacer(),
+			ui.If(
+				!item.HourSpecified,
+				func() *browser.Node { return s.Theme.Text(start.Format("2 Jan 2006")) },
+				func() *browser.Node { return s.Theme.Text(start.Format("2 Jan 2006 @ 3:04 PM")) },
 			),
-		).JustifyContentSpaceBetween(),
+		),
+		ui.OnlyIf(s.Extras.When(s.Item) != "",
+			func() *browser.Node { return s.Theme.Text(s.Extras.When(s.Item)) },
+		),
+		ui.OnlyIf(len(s.Extras.Tags(s.Item)) > 0,
+			func() *browser.Node {
+				return s.Theme.Text(strings.Join(s.Extras.Tags(s.Item), ", ")).Color("gray") // TODO: pull color from theme
+			},
+		),
 		s.Theme.Text(item.Details),
 		ui.OnlyIf(item.Recurs,
-			ui.HStack(
-				s.Theme.Text("This event recurs:").Padding(3),
-				s.Theme.Text(item.RecurString()).Padding(3),
-				ui.OnlyIf(item.UntilSpecified,
-					s.Theme.Textf("Until: %s", item.Until.Format("2 Jan 2006")),
-				),
-			),
+			func() *browser.Node {
+				return ui.HStack(
+					s.Theme.Text("This event recurs:").PaddingPX(3),
+					s.Theme.Text(item.RecurString()).PaddingPX(3),
+					ui.OnlyIf(item.UntilSpecified,
+						func() *browser.Node {
+							return s.Theme.Textf("Until: %s", item.Until.Format("2 Jan 2006")).PaddingPX(3)
+						},
+					),
+					ui.OnlyIf(s.RecurrenceNumber > 0,
+						func() *browser.Node {
+							return s.Theme.Textf("(occurrence %d)", s.RecurrenceNumber).PaddingPX(3)
+						},
+					),
+				).FontSizeEM(0.8)
+			},
 		),
-	).Padding(10)
+	).PaddingPX(10).
+		OnlyIf(s.Color != "", func(n *browser.Node) *browser.Node {
+			return n.BorderLeft(browser.Border{
+				Color: s.Color,
+				Width: browser.Size{Value: 4, Unit: browser.UnitPX},
+				Type:  browser.BorderSolid,
+			})
+		})
 }
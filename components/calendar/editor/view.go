@@ -1,11 +1,17 @@
 package editor
 
 import (
+	"fmt"
+	"sort"
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/manager"
+	"github.com/nlandolfi/elos/web-client/components/calendar/remind"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/nlandolfi/spin/infra/key"
 	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/dom"
 	"github.com/spinsrv/browser/ui"
 )
 
@@ -17,17 +23,50 @@ type State struct {
 
 	Status    string
 	debugging bool
-	Event     *cal.EventItem
+	Event     *cal.EventItem // a copy of Original, saved as the calendar sees fit
+	Extras    extra.Table    `json:"-"`
+
+	// Original is the event being edited, or nil for a new one. At is the
+	// occurrence of it being edited, if it recurs; otherwise it is zero.
+	Original *cal.EventItem `json:"-"`
+	At       time.Time
+
+	// Zones is the names of the time zones to suggest for the event.
+	Zones []string `json:"-"`
+
+	// Calendars is those a new event may go in, and Calendar the index of
+	// the one it goes in.
+	Calendars []manager.CalendarReference `json:"-"`
+	Calendar  int
+
+	// ReadOnly is whether the event is of a calendar which is read only.
+	ReadOnly bool `json:"-"`
+
+	// Tags is the tags of the calendar's events, to suggest for the event,
+	// and TagColors the colors of tags, which the editor sets.
+	Tags      []string          `json:"-"`
+	TagColors map[string]string `json:"-"`
+	tag       string            // being added
 }
 
 type EventCreateEvent struct{}
 type EventAddExclude struct{}
+type EventToggleEnd struct{}
+type EventSetZone struct{ Zone string }
+type EventSetCalendar struct{ Index int }
+
+// EventAddTag adds Tag to the event, or the tag typed if Tag is empty.
+type EventAddTag struct{ Tag string }
+type EventRemoveTag struct{ Tag string }
+type EventSetTagColor struct{ Tag, Color string }
+type EventAddReminder struct{ Before time.Duration }
+type EventRemoveReminder struct{ Before time.Duration }
 type EventDropExcludes struct{ index int }
 type EventToggleDebugging struct{}
 type EventValidate struct{}
 type EventSave struct{}
 type EventCancel struct{}
-type EventDelete struct{ ID string }
+type EventDelete struct{}
 type EventBack struct{}
 type EventSaved struct{}
 
@@ -35,10 +74,66 @@ func (s *State) Handle(e browser.Event) {
 	switch e := e.(type) {
 	case EventAddExclude:
 		s.Event.Excludes = append(s.Event.Excludes, time.Now())
+	case EventToggleEnd:
+		i := s.Extras.Of(s.Event)
+		if i.End.IsZero() {
+			i.End = s.Event.Time
+			if s.Event.HourSpecified {
+				i.End = i.End.Add(time.Hour)
+			}
+		} else {
+			i.End = time.Time{}
+		}
+	case EventSetCalendar:
+		s.Calendar = e.Index
+	case EventSetZone:
+		s.Extras.Of(s.Event).Zone = e.Zone
+	case EventAddTag:
+		tag := extra.Tag(e.Tag)
+		if e.Tag == "" {
+			tag, s.tag = extra.Tag(s.tag), ""
+		}
+		if tag == "" || s.Extras.HasTag(s.Event, tag) {
+			return
+		}
+		i := s.Extras.Of(s.Event)
+		// the tags may be shared with those of the original
+		i.Tags = append(append([]string(nil), i.Tags...), tag)
+	case EventRemoveTag:
+		i := s.Extras.Of(s.Event)
+		var tags []string
+		for _, t := range i.Tags {
+			if t != e.Tag {
+				tags = append(tags, t)
+			}
+		}
+		i.Tags = tags
+	case EventSetTagColor:
+		s.TagColors[e.Tag] = e.Color
+	case EventAddReminder:
+		i := s.Extras.Of(s.Event)
+		for _, b := range i.Reminders {
+			if b == e.Before {
+				return
+			}
+		}
+		// the reminders may be shared with those of the original
+		rs := append(append([]time.Duration(nil), i.Reminders...), e.Before)
+		sort.Slice(rs, func(x, y int) bool { return rs[x] < rs[y] })
+		i.Reminders = rs
+	case EventRemoveReminder:
+		i := s.Extras.Of(s.Event)
+		var rs []time.Duration
+		for _, b := range i.Reminders {
+			if b != e.Before {
+				rs = append(rs, b)
+			}
+		}
+		i.Reminders = rs
 	case EventToggleDebugging:
 		s.debugging = !s.debugging
 	case EventCancel:
-		s.Event = nil
+		s.Event, s.Original = nil, nil
 		s.Status = ""
 	case EventDropExcludes:
 		var es []time.Time
@@ -56,11 +151,32 @@ func (s *State) Handle(e browser.Event) {
 			s.Status = "need a nonzero time"
 			return
 		}
+		if z := s.Extras.Of(s.Event).Zone; z != "" {
+			if _, err := time.LoadLocation(z); err != nil {
+				s.Status = fmt.Sprintf("unknown time zone %q", z)
+				return
+			}
+		}
+		// the times were entered as the times of day in the event's zone
+		s.Extras.InZone(s.Event)
+		if end := s.Extras.Of(s.Event).End; !end.IsZero() {
+			if s.Event.HourSpecified && !end.After(s.Event.Time) {
+				s.Status = "need an end after the start"
+				return
+			}
+			if !s.Event.HourSpecified && extra.Midnight(end).Before(extra.Midnight(s.Event.Time)) {
+				s.Status = "need an end on or after the start"
+				return
+			}
+		}
 		go browser.Dispatch(EventSave{})
 	case EventSaved:
+		if s.Event == nil {
+			return // saved from elsewhere, as by a move
+		}
 		*s.SelectedKey = "day"
 		*s.Time = s.Event.Time
-		s.Event = nil
+		s.Event, s.Original = nil, nil
 		s.Status = ""
 	}
 }
@@ -78,15 +194,30 @@ func View(s *State) *browser.Node {
 	return s.Theme.Card(ui.VStack(
 		ui.HStack(
 			// Header
-			ui.If(s.Event.ID == "",
+			ui.If(s.Original == nil,
 				func() *browser.Node { return s.Theme.Text("Creating event:") },
-				func() *browser.Node { return s.Theme.Textf("Editing event %q:", s.Event.ID) },
+				func() *browser.Node {
+					if !s.At.IsZero() {
+						return s.Theme.Textf("Editing the occurrence on %s of event %q:", s.At.Format("2 Jan 2006"), s.Event.ID)
+					}
+					return s.Theme.Textf("Editing event %q:", s.Event.ID)
+				},
 			).MarginRightPX(10),
 			s.Theme.Text(s.Status),
 		),
 
+		ui.OnlyIf(s.Original == nil && len(s.Calendars) > 1,
+			func() *browser.Node { return calendars(s) },
+		),
+		ui.OnlyIf(s.ReadOnly,
+			func() *browser.Node {
+				return s.Theme.Text("The event's calendar is read only.").Color("gray") // TODO: pull color from theme
+			},
+		),
+
 		s.Theme.TextInput(&(s.Event.Name)).Placeholder("Name"),
 		s.Theme.TextArea(&(s.Event.Details)).Placeholder("Details"),
+		tags(s),
 		ui.VStack(
 			s.Theme.DateInput(&(s.Event.Time)),
 
@@ -99,6 +230,14 @@ func View(s *State) *browser.Node {
 				func() *browser.Node { return s.Theme.TimeInput(&s.Event.Time) },
 			),
 
+			end(s),
+
+			reminders(s),
+
+			ui.OnlyIf(s.Event.HourSpecified,
+				func() *browser.Node { return zone(s) },
+			),
+
 			ui.VStack(
 				ui.HStack(
 					s.Theme.Text("Recurs?"),
@@ -145,11 +284,14 @@ func View(s *State) *browser.Node {
 					s.Theme.Button("Debug").OnClickDispatch(EventToggleDebugging{}),
 				),
 				s.Theme.Button("Cancel").OnClickDispatch(EventCancel{}),
-				ui.OnlyIf(s.Event.ID != "",
+				ui.OnlyIf(s.Original != nil && !s.ReadOnly,
 					func() *browser.Node {
-						return s.Theme.Button("Delete").OnClickDispatch(EventDelete{s.Event.ID})
+						return s.Theme.Button("Delete").OnClickDispatch(EventDelete{})
+					}),
+				ui.OnlyIf(!s.ReadOnly,
+					func() *browser.Node {
+						return s.Theme.Button("Save").OnClickDispatch(EventValidate{})
 					}),
-				s.Theme.Button("Save").OnClickDispatch(EventValidate{}),
 				s.Theme.Button("Back to previous").OnClickDispatch(EventBack{}),
 			),
 
@@ -160,6 +302,177 @@ func View(s *State) *browser.Node {
 	))
 }
 
+func end(s *State) *browser.Node {
+	i := s.Extras.Of(s.Event)
+
+	return ui.VStack(
+		ui.HStack(
+			s.Theme.Text("Ends?"),
+			ui.If(i.End.IsZero(),
+				func() *browser.Node { return s.Theme.Button("Add End").OnClickDispatch(EventToggleEnd{}) },
+				func() *browser.Node { return s.Theme.Button("Drop End").OnClickDispatch(EventToggleEnd{}) },
+			),
+		),
+		ui.OnlyIf(!i.End.IsZero(),
+			func() *browser.Node {
+				return ui.VStack(
+					s.Theme.DateInput(&i.End),
+					ui.OnlyIf(s.Event.HourSpecified,
+						func() *browser.Node { return s.Theme.TimeInput(&i.End) },
+					),
+					ui.OnlyIf(s.Extras.When(s.Event) != "",
+						func() *browser.Node { return s.Theme.Text(s.Extras.When(s.Event)) },
+					),
+				)
+			},
+		),
+	)
+}
+
+// calendars is the picker of the calendar a new event goes in.
+func calendars(s *State) *browser.Node {
+	views := []*browser.Node{s.Theme.Text("Calendar:")}
+	for i, c := range s.Calendars {
+		if c.ReadOnly {
+			continue
+		}
+		views = append(views, s.Theme.Button(c.Path).
+			OnClickDispatch(EventSetCalendar{i}).
+			BorderLeft(browser.Border{
+				Color: c.Color,
+				Width: browser.Size{Value: 4, Unit: browser.UnitPX},
+				Type:  browser.BorderSolid,
+			}).
+			OnlyIf(i == s.Calendar, func(n *browser.Node) *browser.Node {
+				return n.FontWeight("700")
+			}))
+	}
+	return ui.HStack(views...).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap)
+}
+
+// tags is the tags of the event, each with the picker of its color, and
+// the input of those to add.
+func tags(s *State) *browser.Node {
+	views := []*browser.Node{}
+	for _, tag := range s.Extras.Tags(s.Event) {
+		views = append(views, tagView(s, tag))
+	}
+
+	suggestions := []*browser.Node{
+		s.Theme.Text("Add tag:"),
+		s.Theme.TextInput(&s.tag).
+			Placeholder("tag").
+			OnKeyDown(func(ev dom.Event) {
+				if ev.KeyCode() == 13 { // enter
+					go browser.Dispatch(EventAddTag{})
+				}
+			}),
+		s.Theme.Button("Add").OnClickDispatch(EventAddTag{}),
+	}
+	for _, tag := range s.Tags {
+		if !s.Extras.HasTag(s.Event, tag) {
+			suggestions = append(suggestions, s.Theme.Button(tag).OnClickDispatch(EventAddTag{tag}))
+		}
+	}
+
+	return ui.VStack(
+		ui.VStack(views...),
+		ui.HStack(suggestions...).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap),
+	)
+}
+
+func tagView(s *State, tag string) *browser.Node {
+	views := []*browser.Node{
+		s.Theme.Text(tag).
+			PaddingPX(3).
+			BorderRadiusPX(3).
+			OnlyIf(s.TagColors[tag] != "", func(n *browser.Node) *browser.Node {
+				return n.Background(s.TagColors[tag])
+			}),
+		s.Theme.Button("X").OnClickDispatch(EventRemoveTag{tag}).MarginRightPX(10),
+	}
+	for _, c := range manager.Colors {
+		views = append(views, ui.VStack().
+			MinWidth(browser.Size{Value: 16, Unit: browser.UnitPX}).
+			MinHeight(browser.Size{Value: 16, Unit: browser.UnitPX}).
+			Background(c).
+			BorderRadiusPX(3).
+			MarginRightPX(3).
+			OnClickDispatch(EventSetTagColor{tag, c}).
+			OnlyIf(c == s.TagColors[tag], func(n *browser.Node) *browser.Node {
+				return n.Border(browser.Border{
+					Color: s.Theme.TextColor,
+					Width: browser.Size{Value: 2, Unit: browser.UnitPX},
+					Type:  browser.BorderSolid,
+				})
+			}))
+	}
+	views = append(views, s.Theme.Button("No color").OnClickDispatch(EventSetTagColor{tag, ""}))
+	return ui.HStack(views...).AlignItemsCenter()
+}
+
+// reminders is the reminders of the event, and those to add.
+func reminders(s *State) *browser.Node {
+	i := s.Extras.Of(s.Event)
+
+	views := []*browser.Node{s.Theme.Text("Remind:")}
+	set := make(map[time.Duration]bool)
+	for _, b := range i.Reminders {
+		set[b] = true
+		views = append(views,
+			s.Theme.Text(remind.Describe(b)).MarginLeftPX(5),
+			s.Theme.Button("X").OnClickDispatch(EventRemoveReminder{b}),
+		)
+	}
+
+	var offsets []*browser.Node
+	for _, b := range remind.Offsets {
+		if !set[b] {
+			offsets = append(offsets, s.Theme.Button(remind.Describe(b)).OnClickDispatch(EventAddReminder{b}))
+		}
+	}
+
+	return ui.VStack(
+		ui.HStack(views...).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap),
+		ui.HStack(offsets...).FlexWrap(browser.FlexWrapWrap),
+	)
+}
+
+// zone is the picker of the time zone of the event.
+func zone(s *State) *browser.Node {
+	i := s.Extras.Of(s.Event)
+
+	suggestions := []*browser.Node{
+		s.Theme.Text("Time zone:"),
+		s.Theme.TextInput(&i.Zone).Placeholder("that of the time"),
+	}
+	for _, z := range s.Zones {
+		if z != i.Zone {
+			suggestions = append(suggestions, s.Theme.Button(z).OnClickDispatch(EventSetZone{z}))
+		}
+	}
+	if i.Zone != "" {
+		suggestions = append(suggestions, s.Theme.Button("None").OnClickDispatch(EventSetZone{}))
+	}
+
+	return ui.VStack(
+		ui.HStack(suggestions...).FlexWrap(browser.FlexWrapWrap),
+		ui.OnlyIf(i.Zone != "" && s.Time != nil,
+			func() *browser.Node {
+				loc, err := time.LoadLocation(i.Zone)
+				if err != nil {
+					return s.Theme.Textf("unknown time zone %q", i.Zone)
+				}
+				t := s.Event.Time
+				start := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+				return s.Theme.Textf("%s in %s is %s here",
+					start.Format("3:04 PM"), i.Zone, start.In(s.Time.Location()).Format("Mon 3:04 PM MST")).
+					Color("gray") // TODO: pull color from theme
+			},
+		),
+	)
+}
+
 func excludes(t *ui.Theme, e *cal.EventItem) *browser.Node {
 	var views []*browser.Node
 
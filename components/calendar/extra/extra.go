@@ -0,0 +1,306 @@
+// Package extra keeps what the calendar knows about events that
+// cal.EventItem has no field for, such as their IDs, when they end, their
+// tags and their reminders.
+//
+// The extras of a calendar are kept in a JSON file next to its calendar
+// file, each with the name and time of its event, by which they are matched
+// to the events when the two are read back.
+//
+// Since how long an event lasts is among them, the package also expands
+// events into their occurrences, for the views.
+package extra
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Suffix is added to the path of a calendar file for that of its extras.
+const Suffix = ".extra.json"
+
+// Info is the extras of an event.
+type Info struct {
+	// ID is the ID of the event, which cal.EventItem doesn't keep.
+	ID string `json:",omitempty"`
+
+	// End is when the event ends; for events without an hour, it is the
+	// last day of the event. Zero if the event has no end.
+	End time.Time `json:",omitempty"`
+
+	// Series is the ID of the recurring event of which the event is an
+	// override, of the occurrence at Occurrence; the recurring event
+	// excludes it. Empty if the event isn't an override.
+	Series     string    `json:",omitempty"`
+	Occurrence time.Time `json:",omitempty"`
+
+	// Zone is the name of the time zone of the event, as in
+	// "America/New_York". An event with an hour happens at the same time
+	// of day there, whatever the zone it is seen from, and recurs at it
+	// across changes of the clocks. Empty for an event in the zone of its
+	// time, which is how they are read.
+	Zone string `json:",omitempty"`
+
+	// Tags are the tags of the event, as Tag has them.
+	Tags []string `json:",omitempty"`
+
+	// Reminders is how long before the start of each occurrence of the
+	// event to remind of it.
+	Reminders []time.Duration `json:",omitempty"`
+}
+
+// IsZero is whether i says nothing, and needn't be kept.
+func (i *Info) IsZero() bool {
+	return i.ID == "" && i.End.IsZero() && i.Series == "" && i.Zone == "" && len(i.Tags) == 0 && len(i.Reminders) == 0
+}
+
+// Table is the extras of the events of a calendar.
+type Table map[*cal.EventItem]*Info
+
+// Of is the extras of e, which it adds to t if e has none yet.
+func (t Table) Of(e *cal.EventItem) *Info {
+	i, ok := t[e]
+	if !ok {
+		i = new(Info)
+		t[e] = i
+	}
+	return i
+}
+
+// Tag is s as a tag: in lower case, with dashes for spaces.
+func Tag(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), "-")
+}
+
+// Tags is the tags of e.
+func (t Table) Tags(e *cal.EventItem) []string {
+	if i, ok := t[e]; ok {
+		return i.Tags
+	}
+	return nil
+}
+
+// HasTag is whether e has tag.
+func (t Table) HasTag(e *cal.EventItem, tag string) bool {
+	for _, x := range t.Tags(e) {
+		if x == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Location is the time zone of e: that of its Zone, or else of its time,
+// as when the Zone doesn't load, which CheckZones reports.
+func (t Table) Location(e *cal.EventItem) *time.Location {
+	if i, ok := t[e]; ok && i.Zone != "" {
+		if loc, err := time.LoadLocation(i.Zone); err == nil {
+			return loc
+		}
+	}
+	return e.Time.Location()
+}
+
+// CheckZones is the error of the first of es whose Zone doesn't load, as
+// it doesn't where there is no zone database; nil if all do.
+func (t Table) CheckZones(es []*cal.EventItem) error {
+	for _, e := range es {
+		if i, ok := t[e]; ok && i.Zone != "" {
+			if _, err := time.LoadLocation(i.Zone); err != nil {
+				return fmt.Errorf("%s: unknown time zone %q", e.Name, i.Zone)
+			}
+		}
+	}
+	return nil
+}
+
+// InZone puts the times of e, and its end, in its zone, at the times of day
+// they are at now. It is for times set without regard to the zone, as by a
+// person who means the time of day there.
+func (t Table) InZone(e *cal.EventItem) {
+	loc := t.Location(e)
+	in := func(x time.Time) time.Time {
+		if x.IsZero() {
+			return x
+		}
+		return time.Date(x.Year(), x.Month(), x.Day(), x.Hour(), x.Minute(), x.Second(), x.Nanosecond(), loc)
+	}
+
+	e.Time = in(e.Time)
+	e.Until = in(e.Until)
+	for j := range e.Excludes {
+		e.Excludes[j] = in(e.Excludes[j])
+	}
+	if i, ok := t[e]; ok {
+		i.End = in(i.End)
+	}
+}
+
+// Day is 24 hours.
+const Day = 24 * time.Hour
+
+// Midnight is the start of t's day.
+func Midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Days is the number of days from the day of from to the day of to.
+func Days(from, to time.Time) int {
+	return int(math.Round(Midnight(to).Sub(Midnight(from)).Hours() / 24))
+}
+
+// Duration is how long each occurrence of e lasts. An event without an hour
+// lasts at least a day; one with an hour but no end lasts no time at all.
+func (t Table) Duration(e *cal.EventItem) time.Duration {
+	var end time.Time
+	if i, ok := t[e]; ok {
+		end = i.End
+	}
+
+	if !e.HourSpecified {
+		if end.IsZero() || end.Before(e.Time) {
+			return Day
+		}
+		return Midnight(end).AddDate(0, 0, 1).Sub(Midnight(e.Time))
+	}
+
+	if end.IsZero() || end.Before(e.Time) {
+		return 0
+	}
+	return end.Sub(e.Time)
+}
+
+// On is the start and end of the occurrence of e that is on day, if there
+// is one. The occurrence may have started on an earlier day.
+func (t Table) On(e *cal.EventItem, day time.Time) (start, end time.Time, ok bool) {
+	day = Midnight(day)
+	os := t.Occurrences([]*cal.EventItem{e}, day, day.AddDate(0, 0, 1))
+	if len(os) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return os[0].Start, os[0].End, true
+}
+
+// When is when e happens within its days, as in "3:00 PM – 4:00 PM", or
+// "2 Jan 3:00 PM – 4 Jan 9:00 AM" for an event over several days. An event
+// with a zone is said in it, as in "3:00 PM – 4:00 PM EST". It is empty
+// for an event without an hour that lasts a day.
+func (t Table) When(e *cal.EventItem) string {
+	d := t.Duration(e)
+	start := e.Time.In(t.Location(e))
+	end := start.Add(d)
+
+	if !e.HourSpecified {
+		if d <= Day {
+			return ""
+		}
+		return fmt.Sprintf("%s – %s", start.Format("2 Jan"), end.AddDate(0, 0, -1).Format("2 Jan"))
+	}
+
+	var when string
+	switch {
+	case d == 0:
+		when = start.Format("3:04 PM")
+	case Midnight(start).Equal(Midnight(end)):
+		when = fmt.Sprintf("%s – %s", start.Format("3:04 PM"), end.Format("3:04 PM"))
+	default:
+		when = fmt.Sprintf("%s – %s", start.Format("2 Jan 3:04 PM"), end.Format("2 Jan 3:04 PM"))
+	}
+	if i, ok := t[e]; ok && i.Zone != "" {
+		when += start.Format(" MST")
+	}
+	return when
+}
+
+// NewID is a new ID for an event.
+func NewID() string {
+	return uuid.NewV4().String()
+}
+
+// Identify sets the IDs of es to those in t. An event without one, or
+// with one another event already has, is given a new ID, which is put in t
+// so that it is kept when t is written.
+func (t Table) Identify(es []*cal.EventItem) {
+	seen := make(map[string]bool)
+	for _, e := range es {
+		if i, ok := t[e]; ok && i.ID != "" && !seen[i.ID] {
+			e.ID = i.ID
+		} else {
+			e.ID = NewID()
+			t.Of(e).ID = e.ID
+		}
+		seen[e.ID] = true
+	}
+}
+
+// Key identifies e by its name and time.
+func Key(e *cal.EventItem) string {
+	return fmt.Sprintf("%s@%s", e.Name, e.Time.UTC().Format(time.RFC3339))
+}
+
+// entry is the extras of an event in the file, with the name and time of
+// the event when they were written.
+type entry struct {
+	Name string
+	Time time.Time
+	*Info
+}
+
+// Write writes the extras in t of es, in the order of es.
+func Write(w io.Writer, es []*cal.EventItem, t Table) error {
+	entries := []*entry{}
+	for _, e := range es {
+		if i, ok := t[e]; ok && !i.IsZero() {
+			entries = append(entries, &entry{Name: e.Name, Time: e.Time, Info: i})
+		}
+	}
+
+	bs, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bs)
+	return err
+}
+
+// Read reads the extras of es. An empty file has none.
+//
+// The extras of an event are those of the same name and time, the first of
+// them for the first such event, and so on. An event renamed or moved by
+// another client has none, and so is given a new ID by Identify: giving it
+// the extras of another event, by name or time alone, could give it the ID
+// of an event deleted since.
+func Read(r io.Reader, es []*cal.EventItem) (Table, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	t := make(Table)
+	if len(bs) == 0 {
+		return t, nil
+	}
+
+	var entries []*entry
+	if err := json.Unmarshal(bs, &entries); err != nil {
+		return nil, err
+	}
+	used := make([]bool, len(entries))
+	for _, e := range es {
+		for k, n := range entries {
+			if !used[k] && n != nil && n.Info != nil && e.Name == n.Name && e.Time.Equal(n.Time) {
+				c := *n.Info // events must not share extras
+				t[e], used[k] = &c, true
+				break
+			}
+		}
+	}
+	return t, nil
+}
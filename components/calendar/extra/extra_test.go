@@ -0,0 +1,75 @@
+package extra
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// reread writes the extras in t of es, and reads them back for the events
+// of another client.
+func reread(t *testing.T, es []*cal.EventItem, tb Table, others []*cal.EventItem) Table {
+	var b bytes.Buffer
+	if err := Write(&b, es, tb); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(&b, others)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestReadSameNameAndTime(t *testing.T) {
+	a := &cal.EventItem{Name: "standup", Time: date(2022, 3, 1, 9)}
+	b := &cal.EventItem{Name: "standup", Time: date(2022, 3, 1, 9)}
+	tb := Table{a: {ID: "a", Tags: []string{"work"}}, b: {ID: "b"}}
+
+	for i := 0; i < 3; i++ {
+		ra, rb := *a, *b
+		tb = reread(t, []*cal.EventItem{a, b}, tb, []*cal.EventItem{&ra, &rb})
+		if got := tb[&ra].ID; got != "a" {
+			t.Errorf("read %d: first ID = %q, want %q", i, got, "a")
+		}
+		if got := tb[&rb].ID; got != "b" {
+			t.Errorf("read %d: second ID = %q, want %q", i, got, "b")
+		}
+		if len(tb[&rb].Tags) != 0 {
+			t.Errorf("read %d: second has the tags of the first", i)
+		}
+		tb[a], tb[b] = tb[&ra], tb[&rb]
+	}
+}
+
+func TestReadEditedElsewhere(t *testing.T) {
+	lunch := &cal.EventItem{Name: "lunch", Time: date(2022, 3, 1, 12)}
+	gym := &cal.EventItem{Name: "gym", Time: date(2022, 3, 2, 18)}
+	talk := &cal.EventItem{Name: "talk", Time: date(2022, 3, 3, 15)}
+	tb := Table{
+		lunch: {ID: "lunch", End: date(2022, 3, 1, 13)},
+		gym:   {ID: "gym", Tags: []string{"health"}},
+		talk:  {ID: "talk"},
+	}
+
+	// another client moves lunch, and deletes gym for another event at its
+	// time; the talk is as it was
+	moved := &cal.EventItem{Name: "lunch", Time: date(2022, 3, 1, 13)}
+	other := &cal.EventItem{Name: "climbing", Time: date(2022, 3, 2, 18)}
+	same := &cal.EventItem{Name: "talk", Time: date(2022, 3, 3, 15)}
+	got := reread(t, []*cal.EventItem{lunch, gym, talk}, tb, []*cal.EventItem{moved, other, same})
+
+	if i, ok := got[same]; !ok || i.ID != "talk" {
+		t.Errorf("the talk has extras %+v, want those of the talk", i)
+	}
+	for _, e := range []*cal.EventItem{moved, other} {
+		if i, ok := got[e]; ok {
+			t.Errorf("%s has extras %+v, want none", e.Name, i)
+		}
+	}
+
+	got.Identify([]*cal.EventItem{moved, other, same})
+	if moved.ID == "lunch" || other.ID == "gym" || same.ID != "talk" {
+		t.Errorf("IDs are %q, %q and %q, want new ones but the talk's", moved.ID, other.ID, same.ID)
+	}
+}
@@ -0,0 +1,223 @@
+package extra
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// Occurrence is an occurrence of an event.
+type Occurrence struct {
+	*cal.EventItem
+	Start, End time.Time
+
+	// N is which occurrence of a recurring event it is, counting from
+	// one; excluded occurrences keep their numbers. Zero if the event
+	// doesn't recur.
+	N int
+}
+
+// Occurrences is the occurrences of es, which end as t has them, between
+// from and to, in order of their start. An occurrence is between them if
+// it starts at or after from and before to, or starts before from and
+// ends after it.
+//
+// The occurrences are in the zone of from. Those of an event with an hour
+// are at its time of day in its own zone; those of an event without one
+// are on its days in the zone of from.
+func (t Table) Occurrences(es []*cal.EventItem, from, to time.Time) []Occurrence {
+	var os []Occurrence
+	for _, e := range es {
+		os = t.occurrences(os, e, from, to)
+	}
+
+	sort.SliceStable(os, func(i, j int) bool {
+		if !os[i].Start.Equal(os[j].Start) {
+			return os[i].Start.Before(os[j].Start)
+		}
+		return os[i].Name < os[j].Name
+	})
+	return os
+}
+
+// steps are the lengths of the steps between the occurrences of the
+// frequencies of cal.EventItems, at their longest.
+var steps = map[string]time.Duration{
+	"daily":   Day + time.Hour, // a day in which the clocks go back
+	"weekly":  7*Day + time.Hour,
+	"monthly": 31*Day + time.Hour,
+	"yearly":  366*Day + time.Hour,
+}
+
+// occurrences appends to os the occurrences of e between from and to.
+func (t Table) occurrences(os []Occurrence, e *cal.EventItem, from, to time.Time) []Occurrence {
+	d := t.Duration(e)
+	loc := t.Location(e)
+	first := e.Time.In(loc)
+	if !e.HourSpecified {
+		y, m, day := e.Time.Date()
+		loc = from.Location()
+		first = time.Date(y, m, day, 0, 0, 0, 0, loc)
+	}
+	at := func(start time.Time, n int) Occurrence {
+		return Occurrence{e, start.In(from.Location()), start.Add(d).In(from.Location()), n}
+	}
+
+	if !e.Recurs {
+		if during(first, d, from, to) {
+			os = append(os, at(first, 0))
+		}
+		return os
+	}
+
+	step, ok := steps[e.Frequency]
+	if !ok {
+		return t.scan(os, e, first, from, to)
+	}
+	interval := 1
+	if e.IntervalSpecified && e.Interval > 1 {
+		interval = e.Interval
+	}
+
+	// no occurrence before the k-th has ended by from
+	k := 0
+	if skip := from.Sub(first) - d; skip > 0 {
+		k = int(skip/(step*time.Duration(interval))) - 1
+		if k < 0 {
+			k = 0
+		}
+	}
+
+	for ; ; k++ {
+		start, ok := nth(e.Frequency, first, k*interval)
+		if !start.Before(to) || (e.UntilSpecified && Days(e.Until.In(loc), start) > 0) {
+			break
+		}
+		if !ok || !during(start, d, from, to) || excluded(e, start) {
+			continue
+		}
+		os = append(os, at(start, k+1))
+	}
+	return os
+}
+
+// during is whether an occurrence which starts at start and lasts d is
+// between from and to.
+func during(start time.Time, d time.Duration, from, to time.Time) bool {
+	return start.Before(to) && (!start.Before(from) || start.Add(d).After(from))
+}
+
+// nth is the start of the occurrence n steps of frequency after first. It
+// is not ok if there is no such day, as with the 31st of a shorter month,
+// and the start is then in the days after.
+func nth(frequency string, first time.Time, n int) (start time.Time, ok bool) {
+	switch frequency {
+	case "daily":
+		return first.AddDate(0, 0, n), true
+	case "weekly":
+		return first.AddDate(0, 0, 7*n), true
+	case "monthly":
+		start = first.AddDate(0, n, 0)
+		return start, start.Day() == first.Day()
+	case "yearly":
+		start = first.AddDate(n, 0, 0)
+		return start, start.Day() == first.Day()
+	}
+	panic("unknown frequency: " + frequency)
+}
+
+// excluded is whether e excludes its occurrence at start, on its day in
+// the zone of start.
+func excluded(e *cal.EventItem, start time.Time) bool {
+	for _, x := range e.Excludes {
+		if cal.SameDay(x.In(start.Location()), start) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan appends to os the occurrences of e, the first of which is at first,
+// between from and to, as cal.EventItem shows them day by day. It is for
+// frequencies without steps, and slow for events long before the range.
+func (t Table) scan(os []Occurrence, e *cal.EventItem, first, from, to time.Time) []Occurrence {
+	d := t.Duration(e)
+	n := 0
+	for day := Midnight(first); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !e.ShouldDisplayOnDay(day) {
+			continue
+		}
+		n++
+
+		start := time.Date(day.Year(), day.Month(), day.Day(),
+			first.Hour(), first.Minute(), first.Second(), 0, day.Location())
+		if during(start, d, from, to) {
+			os = append(os, Occurrence{e, start.In(from.Location()), start.Add(d).In(from.Location()), n})
+		}
+	}
+	return os
+}
+
+// ByDay is os on each of the days from the day of from. An occurrence is on
+// each of the days it is between the start and end of.
+func ByDay(os []Occurrence, from time.Time, days int) [][]Occurrence {
+	from = Midnight(from)
+	byDay := make([][]Occurrence, days)
+	for _, o := range os {
+		i := Days(from, o.Start)
+		if i < 0 {
+			i = 0
+		}
+		for ; i < days; i++ {
+			day := from.AddDate(0, 0, i)
+			if day.After(o.Start) && !o.End.After(day) {
+				break // it ended
+			}
+			byDay[i] = append(byDay[i], o)
+		}
+	}
+	return byDay
+}
+
+// horizon is how far Next and Previous look for an occurrence.
+const horizon = 10 * 366 * Day
+
+// Next is the first occurrence of e which starts at or after at, within
+// some years. It is in the zone of at.
+func (t Table) Next(e *cal.EventItem, at time.Time) (Occurrence, bool) {
+	// look a month ahead, then further, as most events recur often
+	for d := 31 * Day; ; d *= 4 {
+		if d > horizon {
+			d = horizon
+		}
+		for _, o := range t.Occurrences([]*cal.EventItem{e}, at, at.Add(d)) {
+			if !o.Start.Before(at) {
+				return o, true
+			}
+		}
+		if d == horizon {
+			return Occurrence{}, false
+		}
+	}
+}
+
+// Previous is the last occurrence of e which starts before at, within
+// some years. It is in the zone of at.
+func (t Table) Previous(e *cal.EventItem, at time.Time) (Occurrence, bool) {
+	for d := 31 * Day; ; d *= 4 {
+		if d > horizon {
+			d = horizon
+		}
+		from := at.Add(-d)
+		os := t.Occurrences([]*cal.EventItem{e}, from, at)
+		for i := len(os) - 1; i >= 0; i-- {
+			if !os[i].Start.Before(from) {
+				return os[i], true
+			}
+		}
+		if d == horizon {
+			return Occurrence{}, false
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package extra
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func date(y int, m time.Month, d, h int) time.Time {
+	return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+}
+
+func TestOccurrences(t *testing.T) {
+	cases := []struct {
+		name     string
+		e        *cal.EventItem
+		end      time.Time
+		from, to time.Time
+		want     []time.Time
+		wantN    []int
+	}{
+		{
+			name:  "once",
+			e:     &cal.EventItem{Time: date(2022, 3, 2, 9), HourSpecified: true},
+			from:  date(2022, 3, 1, 0),
+			to:    date(2022, 3, 8, 0),
+			want:  []time.Time{date(2022, 3, 2, 9)},
+			wantN: []int{0},
+		},
+		{
+			name: "weekly by two, with an exclude",
+			e: &cal.EventItem{
+				Time: date(2022, 1, 4, 9), HourSpecified: true,
+				Recurs: true, Frequency: "weekly",
+				IntervalSpecified: true, Interval: 2,
+				Excludes: []time.Time{date(2022, 3, 1, 0)},
+			},
+			from:  date(2022, 2, 10, 0),
+			to:    date(2022, 3, 31, 0),
+			want:  []time.Time{date(2022, 2, 15, 9), date(2022, 3, 15, 9), date(2022, 3, 29, 9)},
+			wantN: []int{4, 6, 7},
+		},
+		{
+			name: "daily until",
+			e: &cal.EventItem{
+				Time:   date(2022, 3, 1, 0),
+				Recurs: true, Frequency: "daily",
+				UntilSpecified: true, Until: date(2022, 3, 3, 0),
+			},
+			from:  date(2022, 2, 1, 0),
+			to:    date(2022, 4, 1, 0),
+			want:  []time.Time{date(2022, 3, 1, 0), date(2022, 3, 2, 0), date(2022, 3, 3, 0)},
+			wantN: []int{1, 2, 3},
+		},
+		{
+			name: "monthly on the 31st",
+			e: &cal.EventItem{
+				Time: date(2022, 1, 31, 12), HourSpecified: true,
+				Recurs: true, Frequency: "monthly",
+			},
+			from:  date(2022, 2, 1, 0),
+			to:    date(2022, 6, 1, 0),
+			want:  []time.Time{date(2022, 3, 31, 12), date(2022, 5, 31, 12)},
+			wantN: []int{3, 5},
+		},
+		{
+			name: "started before the range",
+			e: &cal.EventItem{
+				Time: date(2022, 3, 1, 22), HourSpecified: true,
+				Recurs: true, Frequency: "daily",
+			},
+			end:   date(2022, 3, 2, 2),
+			from:  date(2022, 3, 5, 0),
+			to:    date(2022, 3, 6, 0),
+			want:  []time.Time{date(2022, 3, 4, 22), date(2022, 3, 5, 22)},
+			wantN: []int{4, 5},
+		},
+	}
+
+	for _, c := range cases {
+		tb := make(Table)
+		if !c.end.IsZero() {
+			tb.Of(c.e).End = c.end
+		}
+		os := tb.Occurrences([]*cal.EventItem{c.e}, c.from, c.to)
+		if len(os) != len(c.want) {
+			t.Errorf("%s: got %d occurrences, want %d", c.name, len(os), len(c.want))
+			continue
+		}
+		for i, o := range os {
+			if !o.Start.Equal(c.want[i]) || o.N != c.wantN[i] {
+				t.Errorf("%s: occurrence %d: got %v (%d), want %v (%d)",
+					c.name, i, o.Start, o.N, c.want[i], c.wantN[i])
+			}
+		}
+	}
+}
+
+func TestByDay(t *testing.T) {
+	trip := &cal.EventItem{Name: "Trip", Time: date(2022, 3, 1, 0)}
+	call := &cal.EventItem{Name: "Call", Time: date(2022, 3, 2, 23), HourSpecified: true}
+	tb := Table{
+		trip: {End: date(2022, 3, 2, 0)},
+		call: {End: date(2022, 3, 3, 1)},
+	}
+
+	from := date(2022, 3, 2, 0)
+	byDay := ByDay(tb.Occurrences([]*cal.EventItem{trip, call}, from, from.AddDate(0, 0, 3)), from, 3)
+
+	want := [][]string{{"Trip", "Call"}, {"Call"}, nil}
+	for i, os := range byDay {
+		var got []string
+		for _, o := range os {
+			got = append(got, o.Name)
+		}
+		if len(got) != len(want[i]) {
+			t.Errorf("day %d: got %v, want %v", i, got, want[i])
+			continue
+		}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("day %d: got %v, want %v", i, got, want[i])
+			}
+		}
+	}
+}
+
+func TestOccurrencesAcrossZones(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// the clocks in New York go forward on the 13th of March, 2022
+	e := &cal.EventItem{
+		Time: time.Date(2022, 3, 8, 14, 0, 0, 0, time.UTC), HourSpecified: true,
+		Recurs: true, Frequency: "weekly",
+	}
+	tb := Table{e: {Zone: "America/New_York"}}
+
+	os := tb.Occurrences([]*cal.EventItem{e}, date(2022, 3, 1, 0), date(2022, 3, 20, 0))
+	want := []time.Time{date(2022, 3, 8, 14), date(2022, 3, 15, 13)}
+	if len(os) != len(want) {
+		t.Fatalf("got %d occurrences, want %d", len(os), len(want))
+	}
+	for i, o := range os {
+		if !o.Start.Equal(want[i]) || o.Start.Location() != time.UTC {
+			t.Errorf("occurrence %d: got %v, want %v", i, o.Start, want[i])
+		}
+		if h := o.Start.In(ny).Hour(); h != 9 {
+			t.Errorf("occurrence %d: got %d o'clock in New York, want 9", i, h)
+		}
+	}
+}
+
+func TestInZone(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Paris"); err != nil {
+		t.Skip(err)
+	}
+
+	e := &cal.EventItem{Time: date(2022, 3, 1, 9), HourSpecified: true}
+	tb := Table{e: {Zone: "Europe/Paris", End: date(2022, 3, 1, 10)}}
+	tb.InZone(e)
+
+	if got, want := e.Time, date(2022, 3, 1, 8); !got.Equal(want) {
+		t.Errorf("time: got %v, want %v", got, want)
+	}
+	if got, want := tb[e].End, date(2022, 3, 1, 9); !got.Equal(want) {
+		t.Errorf("end: got %v, want %v", got, want)
+	}
+	if got, want := tb.When(e), "9:00 AM – 10:00 AM CET"; got != want {
+		t.Errorf("when: got %q, want %q", got, want)
+	}
+}
+
+func TestCheckZones(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/Paris"); err != nil {
+		t.Skip(err)
+	}
+
+	paris := &cal.EventItem{Name: "paris", Time: date(2022, 3, 1, 9)}
+	nowhere := &cal.EventItem{Name: "nowhere", Time: date(2022, 3, 1, 9)}
+	tb := Table{paris: {Zone: "Europe/Paris"}, nowhere: {Zone: "Nowhere/At_All"}}
+
+	if err := tb.CheckZones([]*cal.EventItem{paris}); err != nil {
+		t.Errorf("CheckZones(paris) = %v, want nil", err)
+	}
+	if err := tb.CheckZones([]*cal.EventItem{paris, nowhere}); err == nil {
+		t.Error("CheckZones(paris, nowhere) = nil, want an error")
+	}
+	if loc := tb.Location(nowhere); loc != nowhere.Time.Location() {
+		t.Errorf("Location(nowhere) = %v, want that of its time", loc)
+	}
+}
+
+func TestNextAndPrevious(t *testing.T) {
+	e := &cal.EventItem{
+		Time: date(2020, 2, 29, 9), HourSpecified: true,
+		Recurs: true, Frequency: "yearly",
+	}
+	tb := make(Table)
+	at := date(2022, 3, 1, 0)
+
+	if o, ok := tb.Next(e, at); !ok || !o.Start.Equal(date(2024, 2, 29, 9)) || o.N != 5 {
+		t.Errorf("next: got %v (%d), %t; want %v (5)", o.Start, o.N, ok, date(2024, 2, 29, 9))
+	}
+	if o, ok := tb.Previous(e, at); !ok || !o.Start.Equal(date(2020, 2, 29, 9)) || o.N != 1 {
+		t.Errorf("previous: got %v (%d), %t; want %v (1)", o.Start, o.N, ok, date(2020, 2, 29, 9))
+	}
+	if _, ok := tb.Previous(e, date(2020, 2, 29, 9)); ok {
+		t.Errorf("previous of the first: got one, want none")
+	}
+
+	e.UntilSpecified, e.Until = true, date(2023, 1, 1, 0)
+	if o, ok := tb.Next(e, at); ok {
+		t.Errorf("next after until: got %v, want none", o.Start)
+	}
+}
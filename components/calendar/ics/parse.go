@@ -0,0 +1,488 @@
+// Package ics reads and writes iCalendar (RFC 5545) files as events.
+//
+// Only what cal.EventItem, with its extras, can hold is read: the name,
+// details, categories, start and end of a VEVENT, recurrences which repeat at
+// an interval until some time, with exceptions, and the triggers of alarms
+// before the start. Each VEVENT is read into
+// an Entry, which says what of the VEVENT was lost, if anything.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// An Entry is a VEVENT, read as an event.
+type Entry struct {
+	Line  int // where the VEVENT begins
+	Event *cal.EventItem
+	Info  extra.Info
+
+	// Lost is what of the VEVENT the event can't hold. Only entries
+	// which lost nothing should be imported.
+	Lost []string
+}
+
+func (e *Entry) lose(format string, a ...interface{}) {
+	e.Lost = append(e.Lost, fmt.Sprintf(format, a...))
+}
+
+// frequencies are the FREQs of an RRULE, as cal.EventItem has them.
+var frequencies = map[string]string{
+	"DAILY":   "daily",
+	"WEEKLY":  "weekly",
+	"MONTHLY": "monthly",
+	"YEARLY":  "yearly",
+}
+
+// property is a content line, as in "DTSTART;TZID=Europe/Paris:20220301T090000".
+type property struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the VEVENTs of an iCalendar file.
+func Parse(r io.Reader) ([]*Entry, error) {
+	ps, err := properties(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(ps) == 0 || ps[0].name != "BEGIN" || !strings.EqualFold(ps[0].value, "VCALENDAR") {
+		return nil, fmt.Errorf("ics: not a VCALENDAR")
+	}
+
+	var (
+		entries []*Entry
+		stack   []string // of components
+		event   []*property
+		alarms  []*property // the TRIGGERs of its VALARMs
+		start   int
+	)
+	for _, p := range ps {
+		switch p.name {
+		case "BEGIN":
+			stack = append(stack, strings.ToUpper(p.value))
+			if len(stack) == 2 && stack[1] == "VEVENT" {
+				event, alarms, start = nil, nil, p.line
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("ics: line %d: END:%s does not close a component", p.line, p.value)
+			}
+			if len(stack) == 2 && stack[1] == "VEVENT" {
+				entries = append(entries, entry(start, event, alarms))
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if len(stack) == 0 {
+			return nil, fmt.Errorf("ics: line %d: %s outside of the VCALENDAR", p.line, p.name)
+		}
+		// only the properties of the event itself, and not of its alarms
+		if len(stack) == 2 && stack[1] == "VEVENT" {
+			event = append(event, p)
+		}
+		if len(stack) == 3 && stack[1] == "VEVENT" && stack[2] == "VALARM" && p.name == "TRIGGER" {
+			alarms = append(alarms, p)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("ics: %s is not closed", stack[len(stack)-1])
+	}
+
+	return entries, nil
+}
+
+// properties reads the content lines of r, unfolded.
+func properties(r io.Reader) ([]*property, error) {
+	var (
+		ps    []*property
+		lines []string
+		nums  []int
+	)
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if l == "" {
+			continue
+		}
+		// a line which begins with a space or tab continues the last
+		if (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines, nums = append(lines, l), append(nums, n)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, l := range lines {
+		p, err := parseProperty(l)
+		if err != nil {
+			return nil, fmt.Errorf("ics: line %d: %v", nums[i], err)
+		}
+		p.line = nums[i]
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+func parseProperty(l string) (*property, error) {
+	p := &property{params: make(map[string]string)}
+
+	// the name and params end at the first colon which isn't quoted
+	quoted, colon := false, -1
+	for i, c := range l {
+		if c == '"' {
+			quoted = !quoted
+		}
+		if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("no colon in %q", l)
+	}
+	p.value = l[colon+1:]
+
+	parts := splitUnquoted(l[:colon], ';')
+	p.name = strings.ToUpper(parts[0])
+	if p.name == "" {
+		return nil, fmt.Errorf("no name in %q", l)
+	}
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("param %q has no value", param)
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+func splitUnquoted(s string, sep rune) []string {
+	var (
+		parts  []string
+		quoted bool
+		last   int
+	)
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// entry reads the properties of a VEVENT which begins on line, and the
+// TRIGGERs of its alarms.
+func entry(line int, ps, alarms []*property) *Entry {
+	en := &Entry{Line: line, Event: new(cal.EventItem)}
+	e := en.Event
+
+	var dtstart, dtend, duration, rrule *property
+	for _, p := range ps {
+		switch p.name {
+		case "SUMMARY":
+			e.Name = unescape(p.value)
+		case "DESCRIPTION":
+			e.Details = unescape(p.value)
+		case "CATEGORIES":
+			for _, c := range splitText(p.value) {
+				if tag := extra.Tag(c); tag != "" {
+					en.Info.Tags = append(en.Info.Tags, tag)
+				}
+			}
+		case "DTSTART":
+			dtstart = p
+		case "DTEND":
+			dtend = p
+		case "DURATION":
+			duration = p
+		case "RRULE":
+			rrule = p
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				t, _, err := parseTime(v, p.params)
+				if err != nil {
+					en.lose("EXDATE %s: %v", v, err)
+					continue
+				}
+				e.Excludes = append(e.Excludes, t)
+			}
+		case "RDATE":
+			en.lose("RDATE: occurrences on dates of their own")
+		case "RECURRENCE-ID":
+			en.lose("RECURRENCE-ID: a change to one occurrence of another event")
+		case "STATUS":
+			if strings.EqualFold(p.value, "CANCELLED") {
+				en.lose("STATUS: cancelled")
+			}
+		}
+	}
+
+	if dtstart == nil {
+		en.lose("DTSTART: missing")
+		return en
+	}
+	t, date, err := parseTime(dtstart.value, dtstart.params)
+	if err != nil {
+		en.lose("DTSTART: %v", err)
+		return en
+	}
+	e.Time, e.HourSpecified = t, !date
+	if tzid, ok := dtstart.params["TZID"]; ok && !date {
+		en.Info.Zone = tzid
+	}
+
+	switch {
+	case dtend != nil:
+		end, _, err := parseTime(dtend.value, dtend.params)
+		if err != nil {
+			en.lose("DTEND: %v", err)
+			break
+		}
+		en.Info.End = ending(e, end)
+	case duration != nil:
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			en.lose("DURATION: %v", err)
+			break
+		}
+		en.Info.End = ending(e, e.Time.Add(d))
+	}
+
+	if rrule != nil {
+		recur(en, rrule.value)
+	}
+
+	for _, p := range alarms {
+		b, err := trigger(p)
+		if err != nil {
+			en.lose("TRIGGER: %v", err)
+			continue
+		}
+		en.Info.Reminders = append(en.Info.Reminders, b)
+	}
+
+	return en
+}
+
+// ending is the end, as extra.Info has it, of e which ends at end. The end
+// of a VEVENT without an hour is the day after its last.
+func ending(e *cal.EventItem, end time.Time) time.Time {
+	if e.HourSpecified {
+		if !end.After(e.Time) {
+			return time.Time{}
+		}
+		return end
+	}
+	last := end.AddDate(0, 0, -1)
+	if extra.Days(e.Time, last) < 1 {
+		return time.Time{} // a day
+	}
+	return last
+}
+
+// recur reads rule, an RRULE, into the recurrence of en's event.
+func recur(en *Entry, rule string) {
+	e := en.Event
+	e.Recurs = true
+
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			en.lose("RRULE: %q", part)
+			continue
+		}
+		k, v := strings.ToUpper(kv[0]), kv[1]
+
+		switch k {
+		case "FREQ":
+			f, ok := frequencies[strings.ToUpper(v)]
+			if !ok {
+				en.lose("RRULE: FREQ=%s", v)
+				continue
+			}
+			e.Frequency = f
+		case "INTERVAL":
+			i, err := strconv.Atoi(v)
+			if err != nil || i < 1 {
+				en.lose("RRULE: INTERVAL=%s", v)
+				continue
+			}
+			e.Interval, e.IntervalSpecified = i, i > 1
+		case "UNTIL":
+			t, _, err := parseTime(v, nil)
+			if err != nil {
+				en.lose("RRULE: UNTIL=%s: %v", v, err)
+				continue
+			}
+			e.Until, e.UntilSpecified = t, true
+		case "WKST":
+			// the start of the week matters only with BYDAY and an interval
+		case "BYDAY":
+			// the day of the start is implied for weekly events
+			if !strings.EqualFold(v, weekdays[e.Time.Weekday()]) {
+				en.lose("RRULE: BYDAY=%s", v)
+			}
+		case "BYMONTHDAY":
+			if v != strconv.Itoa(e.Time.Day()) {
+				en.lose("RRULE: BYMONTHDAY=%s", v)
+			}
+		case "BYMONTH":
+			if v != strconv.Itoa(int(e.Time.Month())) {
+				en.lose("RRULE: BYMONTH=%s", v)
+			}
+		default:
+			en.lose("RRULE: %s=%s", k, v)
+		}
+	}
+
+	if e.Frequency == "" {
+		en.lose("RRULE: no FREQ")
+	}
+}
+
+var weekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// parseTime reads a DATE or DATE-TIME value, in the zone of its TZID if it
+// has one, and says whether it was a DATE.
+func parseTime(v string, params map[string]string) (t time.Time, date bool, err error) {
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		loc, err = time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(v) == len("20060102"):
+		t, err = time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	case strings.HasSuffix(v, "Z"):
+		t, err = time.Parse("20060102T150405Z", v)
+		return t, false, err
+	default:
+		t, err = time.ParseInLocation("20060102T150405", v, loc)
+		return t, false, err
+	}
+}
+
+// trigger is how long before the start of the event p, a TRIGGER, is.
+func trigger(p *property) (time.Duration, error) {
+	if p.params["VALUE"] == "DATE-TIME" {
+		return 0, fmt.Errorf("at %s, not before the start", p.value)
+	}
+	if p.params["RELATED"] == "END" {
+		return 0, fmt.Errorf("%s before the end", p.value)
+	}
+	if p.value == "" || p.value[0] != '-' {
+		d, err := parseDuration(p.value)
+		if err != nil {
+			return 0, err
+		}
+		if d != 0 {
+			return 0, fmt.Errorf("%s after the start", p.value)
+		}
+		return 0, nil
+	}
+	return parseDuration(p.value[1:])
+}
+
+// parseDuration reads a DURATION value, as in "PT1H30M" or "P1D".
+func parseDuration(v string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(v, "+"), "P")
+	if s == v || s == "" {
+		return 0, fmt.Errorf("bad duration %q", v)
+	}
+
+	var (
+		d      time.Duration
+		inTime bool
+		n      int
+	)
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			n = n*10 + int(c-'0')
+			continue
+		case c == 'T':
+			inTime = true
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * extra.Day
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * extra.Day
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("bad duration %q", v)
+		}
+		n = 0
+	}
+	return d, nil
+}
+
+// splitText splits v, a list of TEXT values, at its commas which aren't
+// escaped, and unescapes the values.
+func splitText(v string) []string {
+	var (
+		vs      []string
+		last    int
+		escaped bool
+	)
+	for i, c := range v {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			vs = append(vs, unescape(v[last:i]))
+			last = i + 1
+		}
+	}
+	return append(vs, unescape(v[last:]))
+}
+
+// unescape undoes the escapes of a TEXT value.
+func unescape(v string) string {
+	var b strings.Builder
+	escaped := false
+	for _, c := range v {
+		if !escaped && c == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped && (c == 'n' || c == 'N') {
+			c = '\n'
+		}
+		b.WriteRune(c)
+		escaped = false
+	}
+	return b.String()
+}
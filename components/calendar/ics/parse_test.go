@@ -0,0 +1,146 @@
+package ics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const parseSrc = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//test//test//EN
+BEGIN:VTIMEZONE
+TZID:America/New_York
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:1@test
+SUMMARY:Standup\, daily
+DESCRIPTION:first line\nsecond
+  line
+DTSTART;TZID=America/New_York:20220301T093000
+DTEND;TZID=America/New_York:20220301T094500
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU;UNTIL=20220601T000000Z
+EXDATE;TZID=America/New_York:20220315T093000,20220329T093000
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:not the event's
+TRIGGER:-PT15M
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Offsite
+DTSTART;VALUE=DATE:20220310
+DTEND;VALUE=DATE:20220313
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20220317
+DTEND;VALUE=DATE:20220318
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Call
+DTSTART:20220302T150000Z
+DURATION:PT1H30M
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Gym
+DTSTART:20220301T070000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=10
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Moved
+RECURRENCE-ID:20220308T150000Z
+DTSTART:20220309T150000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParse(t *testing.T) {
+	es, err := Parse(strings.NewReader(parseSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(es) != 6 {
+		t.Fatalf("got %d entries; want 6", len(es))
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	standup := es[0]
+	if standup.Line != 7 {
+		t.Errorf("standup.Line = %d; want 7", standup.Line)
+	}
+	if len(standup.Lost) > 0 {
+		t.Errorf("standup.Lost = %q; want none", standup.Lost)
+	}
+	e := standup.Event
+	if e.Name != "Standup, daily" || e.Details != "first line\nsecond line" {
+		t.Errorf("standup is %q, %q", e.Name, e.Details)
+	}
+	if want := time.Date(2022, 3, 1, 9, 30, 0, 0, ny); !e.Time.Equal(want) || !e.HourSpecified {
+		t.Errorf("standup starts %s, %t; want %s, true", e.Time, e.HourSpecified, want)
+	}
+	if standup.Info.Zone != "America/New_York" {
+		t.Errorf("standup is in %q; want America/New_York", standup.Info.Zone)
+	}
+	if want := time.Date(2022, 3, 1, 9, 45, 0, 0, ny); !standup.Info.End.Equal(want) {
+		t.Errorf("standup ends %s; want %s", standup.Info.End, want)
+	}
+	if !e.Recurs || e.Frequency != "weekly" || e.Interval != 2 || !e.IntervalSpecified ||
+		!e.UntilSpecified || !e.Until.Equal(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("standup recurs %t %q %d %t %s %t",
+			e.Recurs, e.Frequency, e.Interval, e.IntervalSpecified, e.Until, e.UntilSpecified)
+	}
+	if want := []time.Duration{15 * time.Minute}; !reflect.DeepEqual(standup.Info.Reminders, want) {
+		t.Errorf("standup reminds %s; want %s", standup.Info.Reminders, want)
+	}
+	if want := []time.Time{
+		time.Date(2022, 3, 15, 9, 30, 0, 0, ny),
+		time.Date(2022, 3, 29, 9, 30, 0, 0, ny),
+	}; !reflect.DeepEqual(e.Excludes, want) {
+		t.Errorf("standup excludes %s; want %s", e.Excludes, want)
+	}
+
+	offsite := es[1].Event
+	if offsite.HourSpecified || offsite.Time.Day() != 10 {
+		t.Errorf("offsite starts %s, %t; want the 10th, without an hour", offsite.Time, offsite.HourSpecified)
+	}
+	if end := es[1].Info.End; end.Day() != 12 {
+		t.Errorf("offsite ends %s; want the 12th", end)
+	}
+
+	if end := es[2].Info.End; !end.IsZero() {
+		t.Errorf("holiday ends %s; want no end", end)
+	}
+
+	if want := time.Date(2022, 3, 2, 16, 30, 0, 0, time.UTC); !es[3].Info.End.Equal(want) {
+		t.Errorf("call ends %s; want %s", es[3].Info.End, want)
+	}
+
+	for i, want := range map[int][]string{
+		4: {"RRULE: BYDAY=MO,WE,FR", "RRULE: COUNT=10"},
+		5: {"RECURRENCE-ID: a change to one occurrence of another event"},
+	} {
+		if !reflect.DeepEqual(es[i].Lost, want) {
+			t.Errorf("%s lost %q; want %q", es[i].Event.Name, es[i].Lost, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"BEGIN:VEVENT\nEND:VEVENT\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR\n",
+		"BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VEVENT\n",
+		"BEGIN:VCALENDAR\nSUMMARY\nEND:VCALENDAR\n",
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("Parse(%q): no error", src)
+		}
+	}
+}
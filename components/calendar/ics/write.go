@@ -0,0 +1,207 @@
+package ics
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// now is when the VCALENDARs written are stamped.
+var now = time.Now
+
+// Write writes es, which end as t has them, as a VCALENDAR. A recurrence
+// with a frequency an RRULE can't have is left out, and its event written
+// as a single occurrence.
+func Write(w io.Writer, es []*cal.EventItem, t extra.Table) error {
+	bw := bufio.NewWriter(w)
+	p := &printer{w: bw}
+
+	p.line("BEGIN:VCALENDAR")
+	p.line("VERSION:2.0")
+	p.line("PRODID:-//elos//calendar//EN")
+	p.line("CALSCALE:GREGORIAN")
+	stamp := now().UTC().Format(utc)
+	for _, e := range es {
+		p.event(e, t, stamp)
+	}
+	p.line("END:VCALENDAR")
+
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+const (
+	utc   = "20060102T150405Z"
+	local = "20060102T150405"
+	date  = "20060102"
+)
+
+// UID is the UID of e in the VCALENDARs written. An event without an ID
+// is identified by its key, which changes as it is edited.
+func UID(e *cal.EventItem) string {
+	if e.ID != "" {
+		return e.ID + "@elos"
+	}
+	return fmt.Sprintf("%x@elos", sha1.Sum([]byte(extra.Key(e))))
+}
+
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+// maxLine is the length, in octets, past which lines are folded.
+const maxLine = 75
+
+// line writes a content line, folded.
+func (p *printer) line(l string) {
+	if p.err != nil {
+		return
+	}
+	for len(l) > maxLine {
+		i := maxLine
+		for i > 0 && !utf8Start(l[i]) { // don't split a rune
+			i--
+		}
+		_, p.err = io.WriteString(p.w, l[:i]+"\r\n ")
+		if p.err != nil {
+			return
+		}
+		l = l[i:]
+	}
+	_, p.err = io.WriteString(p.w, l+"\r\n")
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func (p *printer) event(e *cal.EventItem, t extra.Table, stamp string) {
+	p.line("BEGIN:VEVENT")
+	p.line("UID:" + UID(e))
+	p.line("DTSTAMP:" + stamp)
+	p.line("SUMMARY:" + escape(e.Name))
+	if e.Details != "" {
+		p.line("DESCRIPTION:" + escape(e.Details))
+	}
+	if tags := t.Tags(e); len(tags) > 0 {
+		var cs []string
+		for _, tag := range tags {
+			cs = append(cs, escape(tag))
+		}
+		p.line("CATEGORIES:" + strings.Join(cs, ","))
+	}
+
+	d := t.Duration(e)
+	// an event in a zone is written in it, so that it recurs at its time
+	// of day there
+	zone := ""
+	if i, ok := t[e]; ok && i.Zone != "" && e.HourSpecified {
+		zone = i.Zone
+	}
+	loc := t.Location(e)
+	switch {
+	case zone != "":
+		p.line("DTSTART;TZID=" + zone + ":" + e.Time.In(loc).Format(local))
+		if d > 0 {
+			p.line("DTEND;TZID=" + zone + ":" + e.Time.Add(d).In(loc).Format(local))
+		}
+	case e.HourSpecified:
+		p.line("DTSTART:" + e.Time.UTC().Format(utc))
+		if d > 0 {
+			p.line("DTEND:" + e.Time.Add(d).UTC().Format(utc))
+		}
+	default:
+		p.line("DTSTART;VALUE=DATE:" + e.Time.Format(date))
+		p.line("DTEND;VALUE=DATE:" + extra.Midnight(e.Time).AddDate(0, 0, extra.Days(e.Time, e.Time.Add(d))).Format(date))
+	}
+
+	if freq, ok := rfrequencies[e.Frequency]; e.Recurs && ok {
+		rule := "RRULE:FREQ=" + freq
+		if e.IntervalSpecified && e.Interval > 1 {
+			rule += fmt.Sprintf(";INTERVAL=%d", e.Interval)
+		}
+		if e.UntilSpecified {
+			if e.HourSpecified {
+				rule += ";UNTIL=" + e.Until.UTC().Format(utc)
+			} else {
+				rule += ";UNTIL=" + e.Until.Format(date)
+			}
+		}
+		p.line(rule)
+
+		for _, x := range e.Excludes {
+			if e.HourSpecified {
+				// an EXDATE is the start of the occurrence it excludes
+				start := e.Time.In(loc)
+				x = x.In(loc)
+				x = time.Date(x.Year(), x.Month(), x.Day(),
+					start.Hour(), start.Minute(), start.Second(), 0, loc)
+				if zone != "" {
+					p.line("EXDATE;TZID=" + zone + ":" + x.Format(local))
+				} else {
+					p.line("EXDATE:" + x.UTC().Format(utc))
+				}
+			} else {
+				p.line("EXDATE;VALUE=DATE:" + x.Format(date))
+			}
+		}
+	}
+
+	if i, ok := t[e]; ok {
+		for _, b := range i.Reminders {
+			p.line("BEGIN:VALARM")
+			p.line("ACTION:DISPLAY")
+			p.line("DESCRIPTION:" + escape(e.Name))
+			p.line("TRIGGER:-" + formatDuration(b))
+			p.line("END:VALARM")
+		}
+	}
+
+	p.line("END:VEVENT")
+}
+
+// formatDuration is d as a DURATION value, as in "PT1H30M" or "P1D".
+func formatDuration(d time.Duration) string {
+	if d%extra.Day == 0 {
+		return fmt.Sprintf("P%dD", d/extra.Day)
+	}
+	s := "PT"
+	if h := d / time.Hour; h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	if sec := d % time.Minute / time.Second; sec > 0 || s == "PT" {
+		s += fmt.Sprintf("%dS", sec)
+	}
+	return s
+}
+
+// rfrequencies are the FREQs of the frequencies of cal.EventItems.
+var rfrequencies = make(map[string]string)
+
+func init() {
+	for k, v := range frequencies {
+		rfrequencies[v] = k
+	}
+}
+
+// escape escapes v as a TEXT value.
+func escape(v string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+	).Replace(v)
+}
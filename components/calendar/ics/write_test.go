@@ -0,0 +1,141 @@
+package ics
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	now = func() time.Time { return time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	start := time.Date(2022, 3, 1, 9, 30, 0, 0, time.UTC)
+	es := []*cal.EventItem{
+		{
+			Name:          "Standup; daily, " + strings.Repeat("long ", 20),
+			Details:       "first line\nsecond line",
+			Time:          start,
+			HourSpecified: true,
+			Recurs:        true,
+			Frequency:     "weekly",
+			Interval:      2, IntervalSpecified: true,
+			Until: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), UntilSpecified: true,
+			Excludes: []time.Time{start.AddDate(0, 0, 14)},
+		},
+		{
+			Name: "Offsite",
+			Time: time.Date(2022, 3, 10, 0, 0, 0, 0, time.Local),
+		},
+		{
+			Name:          "Call",
+			Time:          time.Date(2022, 3, 2, 15, 0, 0, 0, time.UTC),
+			HourSpecified: true,
+		},
+	}
+	tb := extra.Table{
+		es[0]: {End: start.Add(15 * time.Minute), Reminders: []time.Duration{0, 90 * time.Minute, extra.Day}},
+		es[1]: {End: time.Date(2022, 3, 12, 0, 0, 0, 0, time.Local)},
+	}
+
+	var b bytes.Buffer
+	if err := Write(&b, es, tb); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range strings.Split(b.String(), "\r\n") {
+		if len(l) > maxLine+1 {
+			t.Errorf("line of %d octets: %q", len(l), l)
+		}
+	}
+
+	got, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(es) {
+		t.Fatalf("read %d events; want %d", len(got), len(es))
+	}
+	for i, en := range got {
+		if len(en.Lost) > 0 {
+			t.Errorf("%d lost %q", i, en.Lost)
+		}
+		if !en.Event.Time.Equal(es[i].Time) {
+			t.Errorf("%d starts %s; want %s", i, en.Event.Time, es[i].Time)
+		}
+		en.Event.Time = es[i].Time
+		if !en.Event.Until.IsZero() {
+			en.Event.Until = en.Event.Until.UTC()
+		}
+		for j := range en.Event.Excludes {
+			en.Event.Excludes[j] = en.Event.Excludes[j].UTC()
+		}
+		if !reflect.DeepEqual(en.Event, es[i]) {
+			t.Errorf("%d is\n%+v\nwant\n%+v", i, en.Event, es[i])
+		}
+		if end := tb.Of(es[i]).End; !en.Info.End.Equal(end) {
+			t.Errorf("%d ends %s; want %s", i, en.Info.End, end)
+		}
+		if rs := tb.Of(es[i]).Reminders; !reflect.DeepEqual(en.Info.Reminders, rs) {
+			t.Errorf("%d reminds %s; want %s", i, en.Info.Reminders, rs)
+		}
+	}
+}
+
+func TestWriteZone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	e := &cal.EventItem{
+		Name:          "Standup",
+		Time:          time.Date(2022, 3, 1, 14, 30, 0, 0, time.UTC),
+		HourSpecified: true,
+		Recurs:        true,
+		Frequency:     "weekly",
+	}
+	tb := extra.Table{e: {Zone: "America/New_York"}}
+
+	var b bytes.Buffer
+	if err := Write(&b, []*cal.EventItem{e}, tb); err != nil {
+		t.Fatal(err)
+	}
+	if want := "DTSTART;TZID=America/New_York:20220301T093000\r\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("got\n%s\nwant a line %q", b.String(), want)
+	}
+
+	got, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Info.Zone != "America/New_York" ||
+		!got[0].Event.Time.Equal(time.Date(2022, 3, 1, 9, 30, 0, 0, ny)) {
+		t.Errorf("read %+v", got)
+	}
+}
+
+func TestWriteTags(t *testing.T) {
+	e := &cal.EventItem{Name: "Review", Time: time.Date(2022, 3, 1, 0, 0, 0, 0, time.Local)}
+	tb := extra.Table{e: {Tags: []string{"work", "q1,planning"}}}
+
+	var b bytes.Buffer
+	if err := Write(&b, []*cal.EventItem{e}, tb); err != nil {
+		t.Fatal(err)
+	}
+	if want := `CATEGORIES:work,q1\,planning` + "\r\n"; !strings.Contains(b.String(), want) {
+		t.Errorf("got\n%s\nwant a line %q", b.String(), want)
+	}
+
+	got, err := Parse(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Info.Tags, tb[e].Tags) {
+		t.Errorf("read %+v", got)
+	}
+}
@@ -0,0 +1,150 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/ics"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+type State struct {
+	Theme *ui.Theme `json:"-"`
+
+	Text    string
+	Entries []*ics.Entry `json:"-"`
+	Status  string
+
+	// Exported is the calendar, as an iCalendar file.
+	Exported string `json:"-"`
+}
+
+type EventPreview struct{}
+type EventClear struct{}
+
+// EventExport asks for the calendar to be written to Exported.
+type EventExport struct{}
+
+// EventImport asks for the Entries which lost nothing to be added to the
+// calendar.
+type EventImport struct{ Entries []*ics.Entry }
+
+func (s *State) Handle(e browser.Event) {
+	switch e.(type) {
+	case EventPreview:
+		es, err := ics.Parse(strings.NewReader(s.Text))
+		if err != nil {
+			s.Entries = nil
+			s.Status = err.Error()
+			return
+		}
+		s.Entries = es
+		s.Status = ""
+	case EventClear:
+		s.Text = ""
+		s.Entries = nil
+		s.Status = ""
+	}
+}
+
+// Importable is the entries which lost nothing.
+func (s *State) Importable() []*ics.Entry {
+	var es []*ics.Entry
+	for _, e := range s.Entries {
+		if len(e.Lost) == 0 {
+			es = append(es, e)
+		}
+	}
+	return es
+}
+
+func View(s *State) *browser.Node {
+	importable := s.Importable()
+
+	return ui.VStack(
+		s.Theme.Text("Paste an iCalendar (.ics) file:"),
+		s.Theme.TextArea(&s.Text).
+			Placeholder("BEGIN:VCALENDAR...").
+			FontFamily("monospace").
+			MinHeight(browser.Size{Value: 200, Unit: browser.UnitPX}),
+		ui.HStack(
+			s.Theme.Button("Preview").OnClickDispatch(EventPreview{}),
+			s.Theme.Button("Clear").OnClickDispatch(EventClear{}),
+			ui.OnlyIf(len(importable) > 0,
+				func() *browser.Node {
+					return s.Theme.Button(fmt.Sprintf("Import %d events", len(importable))).
+						OnClickDispatch(EventImport{importable})
+				},
+			),
+			s.Theme.Text(s.Status),
+		).AlignItemsCenter(),
+		ui.OnlyIf(len(s.Entries) > 0,
+			func() *browser.Node { return preview(s) },
+		),
+		ui.HStack(
+			s.Theme.Text("Or export this calendar:"),
+			s.Theme.Button("Export").OnClickDispatch(EventExport{}),
+		).AlignItemsCenter().MarginTopPX(20),
+		ui.OnlyIf(s.Exported != "",
+			func() *browser.Node {
+				return s.Theme.TextArea(&s.Exported).
+					FontFamily("monospace").
+					MinHeight(browser.Size{Value: 200, Unit: browser.UnitPX})
+			},
+		),
+	)
+}
+
+func preview(s *State) *browser.Node {
+	views := make([]*browser.Node, len(s.Entries))
+	for i, e := range s.Entries {
+		views[i] = entryView(s, e)
+	}
+	return ui.VStack(views...).MarginTopPX(10)
+}
+
+func entryView(s *State, e *ics.Entry) *browser.Node {
+	when := e.Event.Time.Format("2 Jan 2006")
+	if e.Event.HourSpecified {
+		when = e.Event.Time.Format("2 Jan 2006 3:04 PM")
+	}
+
+	lost := make([]*browser.Node, len(e.Lost))
+	for i, l := range e.Lost {
+		lost[i] = s.Theme.Text(l).Color("red").FontSizeEM(0.8) // TODO use theme
+	}
+
+	return ui.VStack(
+		ui.HStack(
+			s.Theme.Text(e.Event.Name),
+			ui.Spacer(),
+			s.Theme.Text(when),
+			ui.OnlyIf(e.Event.Recurs,
+				func() *browser.Node { return s.Theme.Text(e.Event.RecurString()).MarginLeftPX(10) },
+			),
+		),
+		ui.OnlyIf(len(lost) > 0,
+			func() *browser.Node {
+				return ui.VStack(
+					append([]*browser.Node{
+						s.Theme.Textf("can't be imported (line %d):", e.Line).FontSizeEM(0.8),
+					}, lost...)...,
+				)
+			},
+		),
+	).
+		PaddingPX(5).
+		BorderBottom(border(s.Theme)).
+		OnlyIf(len(lost) > 0, func(n *browser.Node) *browser.Node {
+			return n.Color("gray")
+		})
+}
+
+func border(th *ui.Theme) browser.Border {
+	return browser.Border{
+		Color: "lightgray", // TODO: use theme?
+		Width: browser.Size{Value: 1, Unit: browser.UnitPX},
+		Type:  browser.BorderSolid,
+	}
+}
@@ -2,7 +2,10 @@ package inspector
 
 import (
 	"log"
+	"strings"
+	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/spinsrv/browser"
 	"github.com/spinsrv/browser/dom"
@@ -14,6 +17,8 @@ type State struct {
 
 	Visible   *bool
 	EventItem **cal.EventItem
+	At        *time.Time // the occurrence inspected
+	Extras    extra.Table
 
 	CurrentX, CurrentY int
 	dragging           bool
@@ -101,9 +106,14 @@ func cardBodyView(s *State) *browser.Node {
 			s.Theme.Text(item.Name).
 				FontSizeEM(1.2),
 		),
-		s.Theme.Text(item.Time.Format("2 Jan 2006")).FontSizeEM(1.0),
-		ui.OnlyIf(item.HourSpecified,
-			func() *browser.Node { return s.Theme.Text(item.Time.Format("3:04 PM")) },
+		s.Theme.Text(occurrence(s, item).Format("2 Jan 2006")).FontSizeEM(1.0),
+		ui.OnlyIf(s.Extras.When(item) != "",
+			func() *browser.Node { return s.Theme.Text(s.Extras.When(item)) },
+		),
+		ui.OnlyIf(len(s.Extras.Tags(item)) > 0,
+			func() *browser.Node {
+				return s.Theme.Text(strings.Join(s.Extras.Tags(item), ", ")).Color("gray") // TODO: pull color from theme
+			},
 		),
 		ui.If(len(item.Details) == 0,
 			func() *browser.Node {
@@ -125,6 +135,14 @@ func cardBodyView(s *State) *browser.Node {
 	)
 }
 
+// occurrence is the start of the occurrence of item inspected.
+func occurrence(s *State, item *cal.EventItem) time.Time {
+	if s.At == nil || s.At.IsZero() {
+		return item.Time
+	}
+	return *s.At
+}
+
 const LeftChevron = "‹"
 const RightChevron = "›"
 
@@ -9,36 +9,128 @@ import (
 type CalendarReference struct {
 	Citizen string
 	Path    string
+
+	// Color is the CSS color of the calendar's events.
+	Color    string
+	Hidden   bool
+	ReadOnly bool
 }
 
-type EventOpenInEditor struct{}
+// EventOpenInEditor asks to open the file of a calendar in the editor.
+type EventOpenInEditor struct{ CalendarReference }
 type EventReloadCalendar struct{}
+type EventAddCalendar struct{}
+type EventRemoveCalendar struct{ Index int }
+type EventToggleHidden struct{ Index int }
+type EventToggleReadOnly struct{ Index int }
 
 type State struct {
 	Theme *ui.Theme
 
+	// Calendars is the calendars subscribed to, which are shown together.
+	Calendars []CalendarReference
+
+	// Calendar is the calendar being added. Before there were several it
+	// was the one loaded; Migrate makes it the first of the Calendars.
 	Calendar CalendarReference
 }
 
+// Colors are the colors given to calendars as they are added.
+var Colors = []string{"#a4c8f0", "#f4b6a6", "#b8e0b0", "#e6c8f0", "#f6dc9c", "#a8dcd8"}
+
+func (s *State) Handle(e browser.Event) {
+	switch e := e.(type) {
+	case EventAddCalendar:
+		if s.Calendar.Path == "" {
+			return
+		}
+		c := s.Calendar
+		if c.Color == "" {
+			c.Color = Colors[len(s.Calendars)%len(Colors)]
+		}
+		s.Calendars = append(s.Calendars, c)
+		s.Calendar = CalendarReference{}
+		go browser.Dispatch(EventReloadCalendar{})
+	case EventRemoveCalendar:
+		var cs []CalendarReference
+		for j, c := range s.Calendars {
+			if j != e.Index {
+				cs = append(cs, c)
+			}
+		}
+		s.Calendars = cs
+		go browser.Dispatch(EventReloadCalendar{})
+	case EventToggleHidden:
+		s.Calendars[e.Index].Hidden = !s.Calendars[e.Index].Hidden
+	case EventToggleReadOnly:
+		s.Calendars[e.Index].ReadOnly = !s.Calendars[e.Index].ReadOnly
+	}
+}
+
+// Migrate makes the calendar loaded before there were several the first of
+// the Calendars.
+func (s *State) Migrate() {
+	if len(s.Calendars) == 0 && s.Calendar.Path != "" {
+		s.Calendar.Color = Colors[0]
+		s.Calendars = []CalendarReference{s.Calendar}
+		s.Calendar = CalendarReference{}
+	}
+}
+
 func View(s *State) *browser.Node {
+	rows := []*browser.Node{s.Theme.Text("The calendars shown:")}
+	for i := range s.Calendars {
+		rows = append(rows, calendarView(s, i))
+	}
+
 	return ui.VStack(
-		s.Theme.Text("The loaded calendar:"),
+		ui.VStack(rows...),
+		s.Theme.Text("Add a calendar:").MarginTopPX(10),
 		ui.HStack(
 			s.Theme.TextInput(&(s.Calendar.Citizen)).
 				Placeholder("citizen").
-				OnKeyDown(func(e dom.Event) {
-					if e.KeyCode() == 13 { // enter
-						go browser.Dispatch(EventReloadCalendar{}) // todo change name of action
-					}
-				}),
+				OnKeyDown(onEnter(EventAddCalendar{})),
 			s.Theme.TextInput(&(s.Calendar.Path)).
 				Placeholder("path").
-				OnKeyDown(func(e dom.Event) {
-					if e.KeyCode() == 13 { // enter
-						go browser.Dispatch(EventReloadCalendar{}) // todo change name of action
-					}
-				}),
-			s.Theme.Button("Open in editor").OnClickDispatch(EventOpenInEditor{}),
+				OnKeyDown(onEnter(EventAddCalendar{})),
+			s.Theme.Button("Add").OnClickDispatch(EventAddCalendar{}),
 		).FlexGrow("1"),
 	)
 }
+
+func calendarView(s *State, i int) *browser.Node {
+	c := &s.Calendars[i]
+	return ui.HStack(
+		ui.VStack().
+			MinWidth(browser.Size{Value: 16, Unit: browser.UnitPX}).
+			MinHeight(browser.Size{Value: 16, Unit: browser.UnitPX}).
+			Background(c.Color).
+			BorderRadiusPX(3).
+			MarginRightPX(5),
+		s.Theme.TextInput(&c.Citizen).
+			Placeholder("citizen").
+			OnKeyDown(onEnter(EventReloadCalendar{})), // todo change name of action
+		s.Theme.TextInput(&c.Path).
+			Placeholder("path").
+			OnKeyDown(onEnter(EventReloadCalendar{})),
+		s.Theme.TextInput(&c.Color).Placeholder("color"),
+		ui.If(c.Hidden,
+			func() *browser.Node { return s.Theme.Button("Show").OnClickDispatch(EventToggleHidden{i}) },
+			func() *browser.Node { return s.Theme.Button("Hide").OnClickDispatch(EventToggleHidden{i}) },
+		),
+		ui.If(c.ReadOnly,
+			func() *browser.Node { return s.Theme.Button("Read only").OnClickDispatch(EventToggleReadOnly{i}) },
+			func() *browser.Node { return s.Theme.Button("Writable").OnClickDispatch(EventToggleReadOnly{i}) },
+		),
+		s.Theme.Button("Open in editor").OnClickDispatch(EventOpenInEditor{*c}),
+		s.Theme.Button("Remove").OnClickDispatch(EventRemoveCalendar{i}),
+	).AlignItemsCenter()
+}
+
+func onEnter(e browser.Event) func(dom.Event) {
+	return func(ev dom.Event) {
+		if ev.KeyCode() == 13 { // enter
+			go browser.Dispatch(e)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package month
 import (
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/elos/web-client/components/calendar/ltr"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/dom"
 	"github.com/spinsrv/browser/ui"
 )
 
@@ -14,10 +16,23 @@ type State struct {
 	Time           *time.Time       `json:"-"`
 	SelectedKey    *string          `json:"-"`
 	EventItems     []*cal.EventItem `json:"-"` // this is a pointer
+	Extras         extra.Table      `json:"-"`
 	hoveredDay     time.Time
 	hoveredEventID string
+	dragging       *drag
 
-	InspectEvent func(e *cal.EventItem) `json:"-"`
+	InspectEvent func(e *cal.EventItem, at time.Time) `json:"-"` // at is the occurrence
+	// MoveEvent moves the occurrence of e at start by days.
+	MoveEvent func(e *cal.EventItem, start time.Time, days int) `json:"-"`
+	// Color, if set, is the color of an event.
+	Color func(e *cal.EventItem) string `json:"-"`
+}
+
+// drag is an event being dragged to another day.
+type drag struct {
+	*cal.EventItem
+	start time.Time // of the occurrence dragged
+	from  time.Time // the day it was dragged from
 }
 
 func (s *State) Handle(e browser.Event) {
@@ -32,31 +47,15 @@ func (s *State) Handle(e browser.Event) {
 			s.hoveredDay = time.Time{}
 		}
 	case EventDecrementMonth:
-		m, y := s.Time.Month(), s.Time.Year()
-
-		if m == time.January {
-			y -= 1
-			m = time.December
-		} else {
-			m -= 1
-		}
-
-		// the three is a hack to avoid time zone nonsense for now
-		*s.Time = time.Date(y, m, 3, 0, 0, 0, 0, s.Time.Location())
+		// the time is in the zone the calendar is seen in, so its month is
+		// the month shown
+		y, m, _ := s.Time.Date()
+		*s.Time = time.Date(y, m-1, 1, 0, 0, 0, 0, s.Time.Location())
 	case EventSetTime:
 		*s.Time = e.Time
 	case EventIncrementMonth:
-		m, y := s.Time.Month(), s.Time.Year()
-
-		if m == time.December {
-			y += 1
-			m = time.January
-		} else {
-			m += 1
-		}
-
-		// the three is a hack to avoid time zone nonsense for now
-		*s.Time = time.Date(y, m, 2, 0, 0, 0, 0, s.Time.Location())
+		y, m, _ := s.Time.Date()
+		*s.Time = time.Date(y, m+1, 1, 0, 0, 0, 0, s.Time.Location())
 	case EventEventHoverStart:
 		s.hoveredEventID = e.ID
 	case EventEventHoverLeave:
@@ -64,7 +63,17 @@ func (s *State) Handle(e browser.Event) {
 			s.hoveredEventID = ""
 		}
 	case EventInspectEvent:
-		s.InspectEvent(e.EventItem)
+		s.InspectEvent(e.EventItem, e.At)
+	case EventDragStart:
+		s.dragging = &drag{e.EventItem, e.Start, e.From}
+	case EventDrop:
+		d := s.dragging
+		s.dragging = nil
+		// dropped where it started, it was a click
+		if d == nil || cal.SameDay(d.from, e.Time) || s.MoveEvent == nil {
+			return
+		}
+		s.MoveEvent(d.EventItem, d.start, extra.Days(d.from, e.Time))
 	}
 }
 
@@ -76,7 +85,15 @@ type EventSetTime struct{ time.Time }
 type EventIncrementMonth struct{}
 type EventEventHoverStart struct{ ID string }
 type EventEventHoverLeave struct{ ID string }
-type EventInspectEvent struct{ *cal.EventItem }
+type EventInspectEvent struct {
+	*cal.EventItem
+	At time.Time
+}
+type EventDragStart struct {
+	*cal.EventItem
+	Start, From time.Time
+}
+type EventDrop struct{ time.Time }
 
 func (s *State) SetTheme(t *ui.Theme) {
 	s.Theme = t
@@ -117,6 +134,12 @@ func grid(s *State) *browser.Node {
 
 	m := int(len(days) / 7)
 
+	from := days[0]
+	byDay := extra.ByDay(
+		s.Extras.Occurrences(s.EventItems, from, from.AddDate(0, 0, len(days))),
+		from, len(days),
+	)
+
 	dayLabels := make([]*browser.Node, 7)
 	for i := 0; i < 7; i++ {
 		dayLabels[i] = s.Theme.Text(days[i].Format("Mon")).
@@ -134,7 +157,7 @@ func grid(s *State) *browser.Node {
 
 		for j := 0; j < 7; j++ {
 			d := days[i*7+j]
-			cols = append(cols, gridSquare(s, d, i == 0, j == 0, i == m-1, j == 7-1))
+			cols = append(cols, gridSquare(s, d, byDay[i*7+j], i == 0, j == 0, i == m-1, j == 7-1))
 		}
 
 		rows = append(rows, ui.HStack(cols...).FlexGrow("1"))
@@ -147,16 +170,18 @@ func grid(s *State) *browser.Node {
 	return ui.VStack(rows...).FlexGrow("1")
 }
 
-func gridSquare(s *State, t time.Time, rowStart, colStart, rowEnd, colEnd bool) *browser.Node {
-	var es cal.EventItems
-	for _, e := range s.EventItems {
-		if e.ShouldDisplayOnDay(t) {
-			es = append(es, e)
-		}
-	}
-	views := make([]*browser.Node, len(es))
-	for i, e := range es {
-		views[i] = lineView(s, e).CursorPointer()
+func gridSquare(s *State, t time.Time, os []extra.Occurrence, rowStart, colStart, rowEnd, colEnd bool) *browser.Node {
+	var views []*browser.Node
+	for _, o := range os {
+		// an event over several days is named where it starts and at the
+		// start of each week, and continues as a bar through the others
+		named := colStart || cal.SameDay(o.Start, t)
+		views = append(views, lineView(s, o.EventItem, o.Start, named).
+			OnlyIf(o.End.After(t.AddDate(0, 0, 1)), func(n *browser.Node) *browser.Node {
+				return n.MarginRightPX(-1) // run into the next square
+			}).
+			OnMouseDown(dragStart(o.EventItem, o.Start, t)).
+			CursorPointer())
 	}
 
 	return ui.VStack(
@@ -167,6 +192,11 @@ func gridSquare(s *State, t time.Time, rowStart, colStart, rowEnd, colEnd bool)
 		FlexBasis("0px").
 		MinWidth(browser.Size{Value: 60, Unit: browser.UnitPX}).
 		MinHeight(browser.Size{Value: 100, Unit: browser.UnitPX}).
+		OnlyIf(s.dragging != nil && cal.SameDay(s.hoveredDay, t), func(n *browser.Node) *browser.Node {
+			return n.Background("#f4f4f4") // TODO use theme
+		}).
+		OnMouseOverCached(t.String(), browser.Dispatcher(EventDayHoverStart{t})).
+		OnMouseUp(browser.Dispatcher(EventDrop{t})).
 		BorderTop(border(s.Theme)).
 		BorderLeft(border(s.Theme)).
 		OnlyIf(rowEnd, func(n *browser.Node) *browser.Node {
@@ -177,6 +207,14 @@ func gridSquare(s *State, t time.Time, rowStart, colStart, rowEnd, colEnd bool)
 		})
 }
 
+// dragStart starts dragging the occurrence of e at start from day.
+func dragStart(e *cal.EventItem, start, day time.Time) func(dom.Event) {
+	return func(ev dom.Event) {
+		ev.PreventDefault() // don't select text
+		go browser.Dispatch(EventDragStart{EventItem: e, Start: start, From: day})
+	}
+}
+
 func dayLineView(s *State, t time.Time) *browser.Node {
 	lcache := t.String()
 	return ui.HStack(
@@ -189,8 +227,8 @@ func dayLineView(s *State, t time.Time) *browser.Node {
 			OnlyIf(s.Time.Month() != t.Month(),
 				func(n *browser.Node) *browser.Node {
 					return n.Color("gray")
-								}).
-			OnlyIf(cal.SameDay(t, time.Now()), // TODO get time from state
+										}).
+			OnlyIf(cal.SameDay(t, time.Now().In(t.Location())), // TODO get time from state
 				func(n *browser.Node) *browser.Node {
 					return n.Color("red") // TODO use theme
 				}).
@@ -218,12 +256,19 @@ func border(th *ui.Theme) browser.Border {
 	}
 }
 
-func lineView(s *State, e *cal.EventItem) *browser.Node {
-	return s.Theme.Text(e.Name).OverflowHidden().
+func lineView(s *State, e *cal.EventItem, start time.Time, named bool) *browser.Node {
+	name := "\u00a0" // keeps the height of the line
+	if named {
+		name = e.Name
+		if e.HourSpecified {
+			name = start.Format("3:04 PM ") + name
+		}
+	}
+	return s.Theme.Text(name).OverflowHidden().
 		FontSizeEM(1).
 		MaxHeight(browser.Size{Value: 1.2, Unit: browser.UnitEM}).
 		Padding(browser.Size{Value: 2, Unit: browser.UnitPX}).
-		Background("lightgray").
+		Background(color(s, e)).
 		Color("black").
 		MarginTopPX(2).
 		OnMouseOverCached(e.ID, browser.Dispatcher(EventEventHoverStart{e.ID})).
@@ -231,10 +276,18 @@ func lineView(s *State, e *cal.EventItem) *browser.Node {
 		OnlyIf(e.ID == s.hoveredEventID, func(n *browser.Node) *browser.Node {
 			return n.BoxShadow(itemLiftedShadow)
 		}).
-		OnClickCached(e.ID, browser.Dispatcher(EventInspectEvent{e})).
+		OnClickCached(e.ID+start.String(), browser.Dispatcher(EventInspectEvent{e, start})).
 		MarginBottomPX(1)
 }
 
+// color is the color of e.
+func color(s *State, e *cal.EventItem) string {
+	if s.Color == nil {
+		return "lightgray" // TODO: use theme?
+	}
+	return s.Color(e)
+}
+
 var itemLiftedShadow = browser.BoxShadow{
 	HOffset: browser.Size{},
 	VOffset: browser.Size{Value: 4, Unit: browser.UnitPX},
@@ -0,0 +1,102 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+// moving is an occurrence of a recurring event dropped on another day,
+// waiting to hear whether to move just it or the whole series.
+type moving struct {
+	*cal.EventItem
+	start time.Time // of the occurrence
+	days  int
+}
+
+type EventMoveOccurrence struct{}
+type EventMoveSeries struct{}
+type EventMoveCancel struct{}
+
+// moveEvent moves the occurrence of e at start by days, keeping its time
+// of day. It asks first if e recurs.
+func (s *State) moveEvent(e *cal.EventItem, start time.Time, days int) {
+	if days == 0 {
+		return
+	}
+	if !s.writable(e) {
+		s.Status = fmt.Sprintf("%q is in a read only calendar", e.Name)
+		return
+	}
+	if e.Recurs {
+		s.Moving = &moving{e, start, days}
+		return
+	}
+	s.moveSeries(e, days)
+	go s.save()
+}
+
+// moveSeries moves e, and so all of its occurrences, by days.
+func (s *State) moveSeries(e *cal.EventItem, days int) {
+	e.Time = e.Time.AddDate(0, 0, days)
+	if i, ok := s.Extras[e]; ok && !i.End.IsZero() {
+		i.End = i.End.AddDate(0, 0, days)
+	}
+	for j := range e.Excludes {
+		e.Excludes[j] = e.Excludes[j].AddDate(0, 0, days)
+	}
+}
+
+// moveOccurrence moves the occurrence of e at start by days, by excluding
+// it from e and adding an override in its place.
+func (s *State) moveOccurrence(e *cal.EventItem, start time.Time, days int) {
+	e.Excludes = append(e.Excludes, start)
+
+	n := s.copyOf(e)
+	n.Recurs = false
+	n.Excludes = nil
+	s.shift(n, start.AddDate(0, 0, days).Sub(e.Time))
+	i := s.Extras.Of(n)
+	i.Series, i.Occurrence = e.ID, start
+	s.add(n)
+}
+
+func (s *State) handleMove(e browser.Event) {
+	if s.Moving == nil {
+		return
+	}
+	m := s.Moving
+
+	switch e.(type) {
+	case EventMoveOccurrence:
+		s.moveOccurrence(m.EventItem, m.start, m.days)
+		go s.save()
+	case EventMoveSeries:
+		s.moveSeries(m.EventItem, m.days)
+		go s.save()
+	case EventMoveCancel:
+	default:
+		return
+	}
+	s.Moving = nil
+}
+
+func moveView(s *State) *browser.Node {
+	m := s.Moving
+	return s.Theme.Card(
+		ui.VStack(
+			s.Theme.Textf("%q recurs. Move:", m.Name).MarginBottomPX(10),
+			ui.HStack(
+				s.Theme.Button("This occurrence").OnClickDispatch(EventMoveOccurrence{}),
+				s.Theme.Button("All occurrences").OnClickDispatch(EventMoveSeries{}),
+				s.Theme.Button("Cancel").OnClickDispatch(EventMoveCancel{}),
+			),
+		).PaddingPX(20),
+	).
+		PositionAbsolute().
+		LeftPX(100).
+		TopPX(100)
+}
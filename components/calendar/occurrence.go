@@ -0,0 +1,290 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/selector"
+	"github.com/nlandolfi/spin/apps/cal"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+// Scope is which occurrences of a recurring event a change is to.
+type Scope int
+
+const (
+	ScopeOccurrence Scope = iota // just the one
+	ScopeFollowing               // the one and those after it
+	ScopeAll
+)
+
+// scoping is a change to an occurrence of a recurring event, waiting to
+// hear which occurrences it is to.
+type scoping struct {
+	delete bool // or save the editor's event
+}
+
+type EventScope struct{ Scope }
+type EventScopeCancel struct{}
+
+// editEvent edits a copy of e, which is nil for a new event. If at is an
+// occurrence of e, the copy starts then.
+func (s *State) editEvent(e *cal.EventItem, at time.Time) {
+	s.LastSelectedItem = selector.Item{
+		Key:     s.SelectorState.SelectedKey,
+		Display: s.SelectorState.SelectedDisplay,
+	}
+
+	draft := new(cal.EventItem)
+	if e != nil {
+		*draft = *e
+		draft.Excludes = append([]time.Time(nil), e.Excludes...)
+		if i, ok := s.Extras[e]; ok {
+			*s.Extras.Of(draft) = *i
+		}
+		s.toZone(draft)
+		if !at.IsZero() && e.Recurs {
+			s.shift(draft, at.Sub(e.Time))
+		} else {
+			at = time.Time{}
+		}
+	} else if s.Zone != "" {
+		s.Extras.Of(draft).Zone = s.Zone
+	}
+
+	s.EditorState.Event = draft
+	s.EditorState.Original = e
+	s.EditorState.At = at
+	s.EditorState.ReadOnly = e != nil && !s.writable(e)
+	s.EditorState.Calendar = 0
+	for i, c := range s.Calendars {
+		if !c.ReadOnly {
+			s.EditorState.Calendar = i
+			break
+		}
+	}
+}
+
+// toZone puts the times of e, and its end, in its zone, so that they are
+// edited at the times of day they are there.
+func (s *State) toZone(e *cal.EventItem) {
+	loc := s.Extras.Location(e)
+	e.Time = e.Time.In(loc)
+	if i, ok := s.Extras[e]; ok && !i.End.IsZero() {
+		i.End = i.End.In(loc)
+	}
+}
+
+// shift moves e, and its end, by d.
+func (s *State) shift(e *cal.EventItem, d time.Duration) {
+	e.Time = e.Time.Add(d)
+	if i, ok := s.Extras[e]; ok && !i.End.IsZero() {
+		i.End = i.End.Add(d)
+	}
+}
+
+// saveEdit saves the editor's event, asking first which occurrences to
+// change if it is an occurrence of a recurring event.
+func (s *State) saveEdit() {
+	if s.EditorState.Original == nil {
+		c := s.calendar()
+		if i := s.EditorState.Calendar; i >= 0 && i < len(s.Calendars) && !s.Calendars[i].ReadOnly {
+			c = s.Calendars[i]
+		}
+		if c == nil {
+			s.EditorState.Status = "no calendar to add the event to"
+			return
+		}
+		s.owners[s.EditorState.Event] = c
+		s.add(s.EditorState.Event) // with its extras
+		go s.save()
+		return
+	}
+	if !s.writable(s.EditorState.Original) {
+		s.EditorState.Status = "the event's calendar is read only"
+		return
+	}
+	if !s.EditorState.At.IsZero() {
+		s.Scoping = &scoping{}
+		return
+	}
+	s.apply(ScopeAll, false)
+}
+
+// deleteEdit deletes the editor's event, asking first which occurrences
+// to delete if it is an occurrence of a recurring event.
+func (s *State) deleteEdit() {
+	if s.EditorState.Original == nil {
+		s.doneEditing()
+		return
+	}
+	if !s.writable(s.EditorState.Original) {
+		s.EditorState.Status = "the event's calendar is read only"
+		return
+	}
+	if !s.EditorState.At.IsZero() {
+		s.Scoping = &scoping{delete: true}
+		return
+	}
+	s.apply(ScopeAll, true)
+}
+
+func (s *State) handleScope(e browser.Event) {
+	if s.Scoping == nil {
+		return
+	}
+
+	switch e := e.(type) {
+	case EventScope:
+		s.apply(e.Scope, s.Scoping.delete)
+	case EventScopeCancel:
+	default:
+		return
+	}
+	s.Scoping = nil
+}
+
+// apply saves, or deletes, the occurrences in scope of the event being
+// edited.
+func (s *State) apply(scope Scope, deleting bool) {
+	s.change(scope, deleting)
+	s.doneEditing()
+	go s.save()
+}
+
+// change changes, or deletes, the occurrences in scope of the event being
+// edited, as apply saves them.
+func (s *State) change(scope Scope, deleting bool) {
+	orig, draft, at := s.EditorState.Original, s.EditorState.Event, s.EditorState.At
+
+	// the first occurrence and those following are all of them
+	if scope == ScopeFollowing && cal.SameDay(at, orig.Time) {
+		scope = ScopeAll
+	}
+
+	switch {
+	case deleting && scope == ScopeOccurrence:
+		orig.Excludes = append(orig.Excludes, at)
+	case deleting && scope == ScopeFollowing:
+		s.end(orig, at)
+		s.removeOverrides(orig, at)
+	case deleting:
+		s.remove(orig)
+		s.removeOverrides(orig, time.Time{})
+	case scope == ScopeOccurrence:
+		orig.Excludes = append(orig.Excludes, at)
+		n := s.copyOf(draft)
+		n.Recurs = false
+		i := s.Extras.Of(n)
+		i.Series, i.Occurrence = orig.ID, at
+		s.add(n)
+	case scope == ScopeFollowing:
+		s.end(orig, at)
+		n := s.copyOf(draft)
+		n.Excludes = nil
+		for _, x := range draft.Excludes {
+			if !x.Before(extra.Midnight(at)) {
+				n.Excludes = append(n.Excludes, x)
+			}
+		}
+		s.add(n)
+		// the overrides of the occurrences which follow are now n's
+		for _, e := range s.EventItems {
+			if i, ok := s.Extras[e]; ok && i.Series == orig.ID && !i.Occurrence.Before(at) {
+				i.Series = n.ID
+			}
+		}
+	default:
+		if !at.IsZero() {
+			s.shift(draft, orig.Time.Sub(at)) // back to the first occurrence
+		}
+		id := orig.ID
+		*orig = *draft
+		orig.ID = id
+		if i, ok := s.Extras[draft]; ok {
+			*s.Extras.Of(orig) = *i
+			s.Extras.Of(orig).ID = id
+		}
+	}
+}
+
+// copyOf is a copy of e, as a new event.
+func (s *State) copyOf(e *cal.EventItem) *cal.EventItem {
+	n := new(cal.EventItem)
+	*n = *e
+	n.ID = extra.NewID()
+	if i, ok := s.Extras[e]; ok {
+		*s.Extras.Of(n) = *i
+	}
+	s.Extras.Of(n).ID = n.ID
+	s.owners[n] = s.owners[e]
+	return n
+}
+
+// end ends the recurrence of e before the day of at.
+func (s *State) end(e *cal.EventItem, at time.Time) {
+	e.Until = extra.Midnight(at).Add(-time.Second)
+	e.UntilSpecified = true
+}
+
+// add adds e to its calendar, or else to the calendar new events go in.
+func (s *State) add(e *cal.EventItem) {
+	if s.owners[e] == nil {
+		s.owners[e] = s.calendar()
+	}
+	if e.ID == "" {
+		e.ID = extra.NewID()
+	}
+	s.Extras.Of(e).ID = e.ID
+	s.EventItems = append(s.EventItems, e)
+}
+
+func (s *State) remove(e *cal.EventItem) {
+	var es []*cal.EventItem
+	for _, i := range s.EventItems {
+		if i.ID != e.ID {
+			es = append(es, i)
+		}
+	}
+	s.EventItems = es
+	delete(s.Extras, e)
+	delete(s.owners, e)
+}
+
+// removeOverrides removes the overrides of the occurrences of e from
+// after, or all of them if after is zero.
+func (s *State) removeOverrides(e *cal.EventItem, after time.Time) {
+	for _, o := range s.EventItems {
+		if i, ok := s.Extras[o]; ok && i.Series == e.ID && !i.Occurrence.Before(after) {
+			s.remove(o)
+		}
+	}
+}
+
+// doneEditing forgets the extras of the copy being edited, which the
+// editor keeps until the save.
+func (s *State) doneEditing() {
+	delete(s.Extras, s.EditorState.Event)
+}
+
+func scopeView(s *State) *browser.Node {
+	verb := "Save"
+	if s.Scoping.delete {
+		verb = "Delete"
+	}
+	return s.Theme.Card(
+		ui.VStack(
+			s.Theme.Textf("%q recurs. %s:", s.EditorState.Event.Name, verb).MarginBottomPX(10),
+			ui.HStack(
+				s.Theme.Button("This occurrence").OnClickDispatch(EventScope{ScopeOccurrence}),
+				s.Theme.Button("This and following").OnClickDispatch(EventScope{ScopeFollowing}),
+				s.Theme.Button("All occurrences").OnClickDispatch(EventScope{ScopeAll}),
+				s.Theme.Button("Cancel").OnClickDispatch(EventScopeCancel{}),
+			),
+		).PaddingPX(20),
+	).
+		PositionAbsolute().
+		LeftPX(100).
+		TopPX(100)
+}
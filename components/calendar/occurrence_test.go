@@ -0,0 +1,212 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// series is a state of a calendar with a weekly standup from Tuesday 1
+// March, which lasts half an hour, and an override of its occurrence on
+// the 8th and one of that on the 22nd. It edits the occurrence on the
+// 15th.
+func series(t *testing.T) (*State, *cal.EventItem) {
+	standup := &cal.EventItem{
+		Name: "standup", Time: at(1, 9), HourSpecified: true,
+		Recurs: true, Frequency: "weekly",
+		Excludes: []time.Time{at(8, 9), at(22, 9)},
+	}
+	s := &State{Calendars: []*Calendar{calendarOf(t, "work", nil, nil)}}
+	s.read()
+
+	s.add(standup)
+	s.Extras.Of(standup).End = at(1, 9).Add(30 * time.Minute)
+	for _, d := range []int{8, 22} {
+		o := &cal.EventItem{Name: "standup", Time: at(d, 10), HourSpecified: true}
+		s.add(o)
+		i := s.Extras.Of(o)
+		i.Series, i.Occurrence = standup.ID, at(d, 9)
+	}
+
+	s.editEvent(standup, at(15, 9))
+	return s, standup
+}
+
+// overrides is the days of the occurrences of e which are overridden.
+func overrides(s *State, e *cal.EventItem) []int {
+	var ds []int
+	for _, o := range s.EventItems {
+		if i, ok := s.Extras[o]; ok && i.Series == e.ID {
+			ds = append(ds, i.Occurrence.Day())
+		}
+	}
+	return ds
+}
+
+func TestEditEvent(t *testing.T) {
+	s, standup := series(t)
+
+	draft := s.EditorState.Event
+	if draft == standup || !draft.Time.Equal(at(15, 9)) {
+		t.Errorf("editing %v, want a copy at the occurrence", draft.Time)
+	}
+	if end := s.Extras[draft].End; !end.Equal(at(15, 9).Add(30 * time.Minute)) {
+		t.Errorf("the copy ends at %v, want half an hour after the occurrence", end)
+	}
+	draft.Excludes[0] = at(29, 9)
+	if !standup.Excludes[0].Equal(at(8, 9)) {
+		t.Error("the copy shares the excludes of the event")
+	}
+}
+
+func TestEnd(t *testing.T) {
+	s, standup := series(t)
+	s.end(standup, at(15, 9))
+	if !standup.UntilSpecified || !standup.Until.Equal(at(15, 0).Add(-time.Second)) {
+		t.Errorf("until %v, want the second before the 15th", standup.Until)
+	}
+	if standup.ShouldDisplayOnDay(at(15, 9)) || !standup.ShouldDisplayOnDay(at(1, 9)) {
+		t.Error("the ended series shows on the 15th, or not on the 1st")
+	}
+}
+
+func TestCopyOf(t *testing.T) {
+	s, standup := series(t)
+	s.Extras.Of(standup).Tags = []string{"team"}
+
+	n := s.copyOf(standup)
+	if n == standup || n.ID == "" || n.ID == standup.ID {
+		t.Fatalf("copy has ID %q, want a new one", n.ID)
+	}
+	if s.Extras[n].ID != n.ID || !s.Extras.HasTag(n, "team") || !s.Extras[n].End.Equal(s.Extras[standup].End) {
+		t.Errorf("copy has extras %+v, want those of the event with its own ID", s.Extras[n])
+	}
+	if s.owners[n] != s.owners[standup] {
+		t.Error("the copy is of another calendar")
+	}
+	s.Extras.Of(n).End = time.Time{}
+	if s.Extras[standup].End.IsZero() {
+		t.Error("the copy shares the extras of the event")
+	}
+}
+
+func TestChangeOccurrence(t *testing.T) {
+	s, standup := series(t)
+	s.EditorState.Event.Name = "long standup"
+	s.change(ScopeOccurrence, false)
+
+	if !standup.Recurs || standup.Name != "standup" {
+		t.Errorf("the series changed to %q", standup.Name)
+	}
+	if last := standup.Excludes[len(standup.Excludes)-1]; !last.Equal(at(15, 9)) {
+		t.Errorf("the series doesn't exclude the 15th: %v", standup.Excludes)
+	}
+	n := named(s, "long standup")
+	if n == nil || n.Recurs || !n.Time.Equal(at(15, 9)) {
+		t.Fatalf("got override %+v, want one on the 15th that doesn't recur", n)
+	}
+	if i := s.Extras[n]; i.Series != standup.ID || !i.Occurrence.Equal(at(15, 9)) {
+		t.Errorf("the override is of %q at %v, want of the series on the 15th", i.Series, i.Occurrence)
+	}
+}
+
+func TestChangeFollowing(t *testing.T) {
+	s, standup := series(t)
+	s.EditorState.Event.Name = "sync"
+	s.change(ScopeFollowing, false)
+
+	if !standup.UntilSpecified || !standup.Until.Before(at(15, 0)) {
+		t.Errorf("the series is until %v, want until before the 15th", standup.Until)
+	}
+	n := named(s, "sync")
+	if n == nil || !n.Recurs || !n.Time.Equal(at(15, 9)) || n.ID == standup.ID {
+		t.Fatalf("got %+v, want a new series from the 15th", n)
+	}
+	if len(n.Excludes) != 1 || !n.Excludes[0].Equal(at(22, 9)) {
+		t.Errorf("the new series excludes %v, want just the 22nd", n.Excludes)
+	}
+	if got := overrides(s, standup); len(got) != 1 || got[0] != 8 {
+		t.Errorf("the series has overrides on %v, want the 8th", got)
+	}
+	if got := overrides(s, n); len(got) != 1 || got[0] != 22 {
+		t.Errorf("the new series has overrides on %v, want the 22nd", got)
+	}
+}
+
+func TestChangeFollowingFirst(t *testing.T) {
+	s, standup := series(t)
+	s.editEvent(standup, at(1, 9))
+	s.EditorState.Event.Name = "sync"
+	n := len(s.EventItems)
+	s.change(ScopeFollowing, false)
+
+	if standup.Name != "sync" || standup.UntilSpecified || len(s.EventItems) != n {
+		t.Error("changing the first occurrence and those following didn't change the series")
+	}
+}
+
+func TestChangeAll(t *testing.T) {
+	s, standup := series(t)
+	id := standup.ID
+	s.EditorState.Event.Name = "sync"
+	s.Extras.Of(s.EditorState.Event).Tags = []string{"team"}
+	n := len(s.EventItems)
+	s.change(ScopeAll, false)
+
+	if standup.Name != "sync" || standup.ID != id || !standup.Time.Equal(at(1, 9)) {
+		t.Errorf("got %q %q at %v, want sync, with its ID, from the first occurrence", standup.Name, standup.ID, standup.Time)
+	}
+	if i := s.Extras[standup]; i.ID != id || !s.Extras.HasTag(standup, "team") || !i.End.Equal(at(1, 9).Add(30*time.Minute)) {
+		t.Errorf("got extras %+v, want those of the edit, from the first occurrence", i)
+	}
+	if len(s.EventItems) != n || len(overrides(s, standup)) != 2 {
+		t.Error("changing all the occurrences added or removed events")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, standup := series(t)
+	s.change(ScopeOccurrence, true)
+	if last := standup.Excludes[len(standup.Excludes)-1]; !last.Equal(at(15, 9)) || len(s.EventItems) != 3 {
+		t.Errorf("deleting an occurrence: excludes %v of %d events", standup.Excludes, len(s.EventItems))
+	}
+
+	s, standup = series(t)
+	s.change(ScopeFollowing, true)
+	if !standup.UntilSpecified || named(s, "standup") != standup {
+		t.Error("deleting the following occurrences didn't end the series")
+	}
+	if got := overrides(s, standup); len(got) != 1 || got[0] != 8 {
+		t.Errorf("deleting the following occurrences left overrides on %v, want the 8th", got)
+	}
+
+	s, standup = series(t)
+	s.change(ScopeAll, true)
+	if len(s.EventItems) != 0 || s.Extras[standup] != nil {
+		t.Errorf("deleting all the occurrences left %d events", len(s.EventItems))
+	}
+}
+
+func TestMove(t *testing.T) {
+	s, standup := series(t)
+	s.moveEvent(standup, at(15, 9), 1)
+	if s.Moving == nil || !standup.Time.Equal(at(1, 9)) {
+		t.Fatal("moving an occurrence of a series didn't ask how")
+	}
+
+	s.moveOccurrence(standup, at(15, 9), 1)
+	n := s.EventItems[len(s.EventItems)-1]
+	if n.Recurs || !n.Time.Equal(at(16, 9)) || s.Extras[n].Series != standup.ID {
+		t.Errorf("moved the occurrence to %+v, want an override on the 16th", n)
+	}
+	if end := s.Extras[n].End; !end.Equal(at(16, 9).Add(30 * time.Minute)) {
+		t.Errorf("the moved occurrence ends at %v, want half an hour after it starts", end)
+	}
+
+	s.moveSeries(standup, 2)
+	if !standup.Time.Equal(at(3, 9)) || !standup.Excludes[0].Equal(at(10, 9)) ||
+		!s.Extras[standup].End.Equal(at(3, 9).Add(30*time.Minute)) {
+		t.Errorf("moved the series to %v, excluding %v", standup.Time, standup.Excludes)
+	}
+}
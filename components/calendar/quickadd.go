@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/quickadd"
+	"github.com/nlandolfi/spin/apps/cal"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/dom"
+	"github.com/spinsrv/browser/ui"
+)
+
+// EventQuickAdd adds the events of the QuickAdd phrase.
+type EventQuickAdd struct{}
+type EventClearQuickAdd struct{}
+
+// quickAdd adds the events of the QuickAdd phrase to the calendar new
+// events go in, and saves them.
+func (s *State) quickAdd() {
+	es, err := quickadd.Parse(s.QuickAdd, time.Now().In(s.location()))
+	if err != nil {
+		s.Status = err.Error()
+		return
+	}
+	c := s.calendar()
+	if c == nil {
+		s.Status = "no calendar to add to"
+		return
+	}
+
+	for _, e := range es {
+		if s.Zone != "" {
+			s.Extras.Of(e).Zone = s.Zone
+		}
+		s.owners[e] = c
+		s.add(e)
+	}
+	s.QuickAdd = ""
+	s.Time = es[0].Time
+	go s.save()
+}
+
+// quickAddBox is the box to type the phrase of events to add in.
+func quickAddBox(s *State) *browser.Node {
+	return s.Theme.TextInput(&s.QuickAdd).
+		Placeholder("Quick add, as in \"lunch tomorrow 12:30\"").
+		OnKeyDown(func(ev dom.Event) {
+			if ev.KeyCode() == 13 { // enter
+				go browser.Dispatch(EventQuickAdd{})
+			}
+		}).
+		MarginLeftPX(5)
+}
+
+// quickAddView is a preview of the events of the QuickAdd phrase, as they
+// would be added.
+func quickAddView(s *State) *browser.Node {
+	es, err := quickadd.Parse(s.QuickAdd, time.Now().In(s.location()))
+
+	var views []*browser.Node
+	for _, e := range es {
+		views = append(views, previewView(s, e))
+	}
+
+	return s.Theme.Card(
+		ui.VStack(
+			ui.HStack(
+				s.Theme.Text("Quick add:"),
+				ui.Spacer(),
+				s.Theme.Button("x").OnClickDispatch(EventClearQuickAdd{}),
+			).AlignItemsCenter(),
+			ui.If(err != nil,
+				func() *browser.Node {
+					return s.Theme.Text(err.Error()).Color("gray") // TODO: pull color from theme
+				},
+				func() *browser.Node { return ui.VStack(views...) },
+			),
+			ui.OnlyIf(err == nil,
+				func() *browser.Node {
+					return ui.HStack(
+						s.Theme.Button("Add").OnClickDispatch(EventQuickAdd{}),
+						ui.OnlyIf(s.calendar() != nil,
+							func() *browser.Node {
+								return s.Theme.Textf("to %s", s.calendar().Path).Color("gray").MarginLeftPX(5) // TODO: pull color from theme
+							},
+						),
+					).AlignItemsCenter().MarginTopPX(5)
+				},
+			),
+		).PaddingPX(10),
+	).MinWidth(browser.Size{Value: 300, Unit: browser.UnitPX})
+}
+
+func previewView(s *State, e *cal.EventItem) *browser.Node {
+	layout := "Mon 2 Jan 2006"
+	if e.HourSpecified {
+		layout = "Mon 2 Jan 2006, 3:04 PM"
+	}
+	recurrence := ""
+	if e.Recurs {
+		recurrence = e.RecurString()
+		if e.UntilSpecified {
+			recurrence += ", until " + e.Until.Format("2 Jan 2006")
+		}
+	}
+
+	return ui.VStack(
+		s.Theme.Text(e.Name).FontSizeEM(1.1),
+		s.Theme.Text(e.Time.Format(layout)),
+		ui.OnlyIf(recurrence != "",
+			func() *browser.Node {
+				return s.Theme.Text(recurrence).Color("gray") // TODO: pull color from theme
+			},
+		),
+	).MarginTopPX(5)
+}
@@ -0,0 +1,410 @@
+// Package quickadd reads events from phrases, as in "lunch with Sam
+// tomorrow 12:30", "standup every weekday 9am until Dec 1" or "rent
+// monthly on the 1st".
+//
+// A phrase is read word by word. The words of the day, time and recurrence
+// of the event are read as such; the others are its name.
+package quickadd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+var (
+	ErrEmpty      = errors.New("quickadd: nothing to add")
+	ErrNoName     = errors.New("quickadd: no name")
+	ErrUntilAlone = errors.New("quickadd: until, but no recurrence")
+)
+
+// phrase is what has been read of a phrase.
+type phrase struct {
+	name []string
+
+	date     time.Time // a day, if not zero
+	weekday  *time.Weekday
+	next     bool // the weekday of next week
+	monthDay int  // the day of the month, if not 0
+
+	hour, minute int
+	timed        bool
+
+	frequency string
+	interval  int
+	weekdays  bool // every weekday
+	until     time.Time
+}
+
+// Parse reads the events of s, at now, in whose zone they are. It is
+// usually one event; "every weekday" is one weekly event for each day from
+// Monday to Friday.
+func Parse(s string, now time.Time) ([]*cal.EventItem, error) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return nil, ErrEmpty
+	}
+
+	p := &phrase{}
+	today := extra.Midnight(now)
+	for i := 0; i < len(words); {
+		if n := p.read(words[i:], today); n > 0 {
+			i += n
+			continue
+		}
+		p.name = append(p.name, words[i])
+		i++
+	}
+
+	if len(p.name) == 0 {
+		return nil, ErrNoName
+	}
+	if !p.until.IsZero() && p.frequency == "" {
+		return nil, ErrUntilAlone
+	}
+
+	day := p.day(today)
+	if p.timed && p.date.IsZero() && p.weekday == nil && p.monthDay == 0 && p.frequency == "" &&
+		p.at(day).Before(now) {
+		day = day.AddDate(0, 0, 1) // the time has passed today
+	}
+
+	if !p.weekdays {
+		return []*cal.EventItem{p.event(day)}, nil
+	}
+	var es []*cal.EventItem
+	for d := time.Monday; d <= time.Friday; d++ {
+		es = append(es, p.event(onOrAfter(day, d)))
+	}
+	return es, nil
+}
+
+// connectives are the words which may come before the day or time of an
+// event, as in "on the 1st", and which are then not of its name.
+var connectives = map[string]bool{"at": true, "on": true, "the": true}
+
+// read reads the day, time or recurrence at the start of words, after
+// some connectives, and is how many words it read; 0 if there was none.
+func (p *phrase) read(words []string, today time.Time) int {
+	for k := 0; k < len(words) && k <= 2; k++ {
+		if k > 0 && !connectives[clean(words[k-1])] {
+			return 0
+		}
+		for _, r := range []func([]string, time.Time) int{
+			p.readUntil, p.readEvery, p.readFrequency, p.readDate, p.readTime,
+		} {
+			if n := r(words[k:], today); n > 0 {
+				return k + n
+			}
+		}
+	}
+	return 0
+}
+
+func (p *phrase) readUntil(words []string, today time.Time) int {
+	if clean(words[0]) != "until" || len(words) < 2 {
+		return 0
+	}
+	t, n := date(words[1:], today)
+	if n == 0 {
+		return 0
+	}
+	p.until = t
+	return n + 1
+}
+
+var units = map[string]string{
+	"day": "daily", "days": "daily",
+	"week": "weekly", "weeks": "weekly",
+	"month": "monthly", "months": "monthly",
+	"year": "yearly", "years": "yearly",
+}
+
+func (p *phrase) readEvery(words []string, today time.Time) int {
+	if clean(words[0]) != "every" || len(words) < 2 {
+		return 0
+	}
+
+	i, interval := 1, 1
+	if w := clean(words[i]); w == "other" {
+		i, interval = i+1, 2
+	} else if n, err := strconv.Atoi(w); err == nil && n > 0 {
+		i, interval = i+1, n
+	}
+	if i >= len(words) {
+		return 0
+	}
+
+	w := clean(words[i])
+	if f, ok := units[w]; ok {
+		p.frequency, p.interval = f, interval
+		return i + 1
+	}
+	if interval != 1 {
+		return 0 // as in "every 2 mondays"
+	}
+	if w == "weekday" {
+		p.frequency, p.weekdays = "weekly", true
+		return i + 1
+	}
+	if d, ok := weekday(w); ok {
+		p.frequency, p.weekday = "weekly", &d
+		return i + 1
+	}
+	return 0
+}
+
+var frequencies = map[string]string{
+	"daily":    "daily",
+	"weekly":   "weekly",
+	"monthly":  "monthly",
+	"yearly":   "yearly",
+	"annually": "yearly",
+}
+
+func (p *phrase) readFrequency(words []string, today time.Time) int {
+	f, ok := frequencies[clean(words[0])]
+	if !ok {
+		return 0
+	}
+	p.frequency, p.interval = f, 1
+	return 1
+}
+
+func (p *phrase) readDate(words []string, today time.Time) int {
+	w := clean(words[0])
+	switch w {
+	case "today":
+		p.date = today
+		return 1
+	case "tomorrow":
+		p.date = today.AddDate(0, 0, 1)
+		return 1
+	case "next":
+		if len(words) > 1 {
+			if d, ok := weekday(clean(words[1])); ok {
+				p.weekday, p.next = &d, true
+				return 2
+			}
+		}
+		return 0
+	}
+	if d, ok := weekday(w); ok {
+		p.weekday = &d
+		return 1
+	}
+	if t, n := date(words, today); n > 0 {
+		p.date = t
+		return n
+	}
+	// a number alone is of the name, as in "lunch with 2 friends"
+	if d, ok := ordinal(w); ok && strings.ContainsAny(w, "stndrh") {
+		p.monthDay = d
+		return 1
+	}
+	return 0
+}
+
+func (p *phrase) readTime(words []string, today time.Time) int {
+	w := clean(words[0])
+	switch w {
+	case "noon":
+		p.hour, p.minute, p.timed = 12, 0, true
+		return 1
+	case "midnight":
+		p.hour, p.minute, p.timed = 0, 0, true
+		return 1
+	}
+
+	// the meridiem may be a word of its own, as in "9 am"
+	n := 1
+	if len(words) > 1 {
+		if m := clean(words[1]); m == "am" || m == "pm" {
+			w, n = w+m, 2
+		}
+	}
+	h, m, ok := clock(w)
+	if !ok {
+		return 0
+	}
+	p.hour, p.minute, p.timed = h, m, true
+	return n
+}
+
+// clock reads a time of day, as in "9am", "9:30pm" or "14:30".
+func clock(w string) (hour, minute int, ok bool) {
+	meridiem := ""
+	if strings.HasSuffix(w, "am") || strings.HasSuffix(w, "pm") {
+		w, meridiem = w[:len(w)-2], w[len(w)-2:]
+	}
+	if meridiem == "" && !strings.Contains(w, ":") {
+		return 0, 0, false // a number alone is of the name
+	}
+
+	parts := strings.SplitN(w, ":", 2)
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, false
+	}
+	if len(parts) == 2 {
+		if len(parts[1]) != 2 {
+			return 0, 0, false
+		}
+		minute, err = strconv.Atoi(parts[1])
+		if err != nil || minute < 0 || minute > 59 {
+			return 0, 0, false
+		}
+	}
+
+	switch meridiem {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, true
+}
+
+var months = map[string]time.Month{}
+
+func init() {
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		months[name] = m
+		months[name[:3]] = m
+	}
+	months["sept"] = time.September
+}
+
+// date reads a day of a month, as in "Dec 1", "1st December" or "12/1",
+// and is how many words it read; 0 if there was none. The day is the first
+// such on or after today.
+func date(words []string, today time.Time) (time.Time, int) {
+	var (
+		m time.Month
+		d int
+		n int
+	)
+
+	w := clean(words[0])
+	if parts := strings.Split(w, "/"); len(parts) == 2 {
+		mm, err1 := strconv.Atoi(parts[0])
+		dd, err2 := strconv.Atoi(parts[1])
+		if err1 != nil || err2 != nil || mm < 1 || mm > 12 {
+			return time.Time{}, 0
+		}
+		m, d, n = time.Month(mm), dd, 1
+	} else if len(words) > 1 {
+		if mm, ok := months[w]; ok {
+			if dd, ok := ordinal(clean(words[1])); ok {
+				m, d, n = mm, dd, 2
+			}
+		} else if dd, ok := ordinal(w); ok {
+			if mm, ok := months[clean(words[1])]; ok {
+				m, d, n = mm, dd, 2
+			}
+		}
+	}
+	if n == 0 || d < 1 || d > 31 {
+		return time.Time{}, 0
+	}
+
+	t := time.Date(today.Year(), m, d, 0, 0, 0, 0, today.Location())
+	if t.Before(today) {
+		t = time.Date(today.Year()+1, m, d, 0, 0, 0, 0, today.Location())
+	}
+	if t.Month() != m {
+		return time.Time{}, 0 // as February 30
+	}
+	return t, n
+}
+
+// ordinal reads a day of the month, as in "1", "1st" or "22nd".
+func ordinal(w string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		w = strings.TrimSuffix(w, suffix)
+	}
+	d, err := strconv.Atoi(w)
+	if err != nil || d < 1 || d > 31 {
+		return 0, false
+	}
+	return d, true
+}
+
+// weekday reads a day of the week, as in "Monday", "mondays" or "mon".
+// "Sat" and "sun" are words of their own.
+func weekday(w string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if w == name || w == name+"s" || (w == name[:3] && w != "sat" && w != "sun") {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// clean is w in lower case, without the punctuation around it.
+func clean(w string) string {
+	return strings.ToLower(strings.Trim(w, ",.;!?()\""))
+}
+
+// day is the day of the event, from today.
+func (p *phrase) day(today time.Time) time.Time {
+	switch {
+	case !p.date.IsZero():
+		return p.date
+	case p.weekday != nil:
+		d := onOrAfter(today, *p.weekday)
+		if p.next && d.Equal(today) {
+			d = d.AddDate(0, 0, 7)
+		}
+		return d
+	case p.monthDay != 0:
+		for d := today; ; d = d.AddDate(0, 0, 1) {
+			if d.Day() == p.monthDay {
+				return d
+			}
+		}
+	default:
+		return today
+	}
+}
+
+// at is the time of the event on day.
+func (p *phrase) at(day time.Time) time.Time {
+	if !p.timed {
+		return day
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location())
+}
+
+func (p *phrase) event(day time.Time) *cal.EventItem {
+	e := &cal.EventItem{
+		Name:          strings.Join(p.name, " "),
+		Time:          p.at(day),
+		HourSpecified: p.timed,
+	}
+	if p.frequency != "" {
+		e.Recurs, e.Frequency = true, p.frequency
+		if p.interval > 1 {
+			e.Interval, e.IntervalSpecified = p.interval, true
+		}
+		if !p.until.IsZero() {
+			e.Until, e.UntilSpecified = p.until, true
+		}
+	}
+	return e
+}
+
+// onOrAfter is the first day which is a d, on or after day.
+func onOrAfter(day time.Time, d time.Weekday) time.Time {
+	return day.AddDate(0, 0, (int(d)-int(day.Weekday())+7)%7)
+}
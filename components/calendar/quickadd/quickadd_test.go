@@ -0,0 +1,113 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func at(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	now := at(2022, 3, 2, 10, 0) // a Wednesday
+
+	standup := func(d int) *cal.EventItem {
+		return &cal.EventItem{
+			Name: "standup", Time: at(2022, 3, d, 9, 0), HourSpecified: true,
+			Recurs: true, Frequency: "weekly",
+			Until: at(2022, 12, 1, 0, 0), UntilSpecified: true,
+		}
+	}
+
+	cases := []struct {
+		phrase string
+		want   []*cal.EventItem
+	}{
+		{
+			"lunch with Sam tomorrow 12:30",
+			[]*cal.EventItem{{Name: "lunch with Sam", Time: at(2022, 3, 3, 12, 30), HourSpecified: true}},
+		},
+		{
+			"standup every weekday 9am until Dec 1",
+			[]*cal.EventItem{standup(7), standup(8), standup(2), standup(3), standup(4)},
+		},
+		{
+			"rent monthly on the 1st",
+			[]*cal.EventItem{{Name: "rent", Time: at(2022, 4, 1, 0, 0), Recurs: true, Frequency: "monthly"}},
+		},
+		{
+			"Dentist next wed at 3:15pm",
+			[]*cal.EventItem{{Name: "Dentist", Time: at(2022, 3, 9, 15, 15), HourSpecified: true}},
+		},
+		{
+			"call mom 9am",
+			[]*cal.EventItem{{Name: "call mom", Time: at(2022, 3, 3, 9, 0), HourSpecified: true}},
+		},
+		{
+			"lunch with 2 friends at noon",
+			[]*cal.EventItem{{Name: "lunch with 2 friends", Time: at(2022, 3, 2, 12, 0), HourSpecified: true}},
+		},
+		{
+			"lunch at the office",
+			[]*cal.EventItem{{Name: "lunch at the office", Time: at(2022, 3, 2, 0, 0)}},
+		},
+		{
+			"review every other week on friday 2 pm",
+			[]*cal.EventItem{{
+				Name: "review", Time: at(2022, 3, 4, 14, 0), HourSpecified: true,
+				Recurs: true, Frequency: "weekly", Interval: 2, IntervalSpecified: true,
+			}},
+		},
+		{
+			"yoga every tuesday 18:00",
+			[]*cal.EventItem{{
+				Name: "yoga", Time: at(2022, 3, 8, 18, 0), HourSpecified: true,
+				Recurs: true, Frequency: "weekly",
+			}},
+		},
+		{
+			"Ana's birthday July 4th yearly",
+			[]*cal.EventItem{{Name: "Ana's birthday", Time: at(2022, 7, 4, 0, 0), Recurs: true, Frequency: "yearly"}},
+		},
+		{
+			"offsite 1/15",
+			[]*cal.EventItem{{Name: "offsite", Time: at(2023, 1, 15, 0, 0)}},
+		},
+	}
+
+	for _, c := range cases {
+		got, err := Parse(c.phrase, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", c.phrase, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q):", c.phrase)
+			for _, e := range got {
+				t.Errorf("\tgot %+v", e)
+			}
+			for _, e := range c.want {
+				t.Errorf("\twant %+v", e)
+			}
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := at(2022, 3, 2, 10, 0)
+
+	for phrase, want := range map[string]error{
+		"  ":               ErrEmpty,
+		"tomorrow 9am":     ErrNoName,
+		"gym until Dec 1":  ErrUntilAlone,
+		"on the 1st daily": ErrNoName,
+	} {
+		if _, err := Parse(phrase, now); err != want {
+			t.Errorf("Parse(%q) = %v, want %v", phrase, err, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package remind finds the reminders of the occurrences of events which
+// are due, for the calendar to notify of.
+package remind
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// Reminder is a reminder of an occurrence, Before its start, due At.
+type Reminder struct {
+	extra.Occurrence
+	Before time.Duration
+	At     time.Time
+}
+
+// Offsets are the reminders the editor suggests, as how long before the
+// start of an occurrence they are.
+var Offsets = []time.Duration{
+	0,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+	extra.Day,
+	7 * extra.Day,
+}
+
+// Catchup is how long reminders are still given after they were due, as
+// when the calendar was closed at the time. Older ones are dropped.
+const Catchup = 15 * time.Minute
+
+// Due is the reminders of es, which end and are reminded of as t has them,
+// due after from and at or before to, the soonest first.
+func Due(es []*cal.EventItem, t extra.Table, from, to time.Time) []Reminder {
+	var rs []Reminder
+	for _, e := range es {
+		i, ok := t[e]
+		if !ok || len(i.Reminders) == 0 {
+			continue
+		}
+
+		var most time.Duration
+		for _, b := range i.Reminders {
+			if b > most {
+				most = b
+			}
+		}
+		// the occurrences which start up to the longest reminder after to
+		for _, o := range t.Occurrences([]*cal.EventItem{e}, from, to.Add(most+time.Nanosecond)) {
+			for _, b := range i.Reminders {
+				if at := o.Start.Add(-b); at.After(from) && !at.After(to) {
+					rs = append(rs, Reminder{Occurrence: o, Before: b, At: at})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		if !rs[i].At.Equal(rs[j].At) {
+			return rs[i].At.Before(rs[j].At)
+		}
+		return rs[i].Name < rs[j].Name
+	})
+	return rs
+}
+
+// Describe is b, as in "10 minutes before".
+func Describe(b time.Duration) string {
+	switch {
+	case b <= 0:
+		return "at the start"
+	case b%(7*extra.Day) == 0:
+		return plural(int(b/(7*extra.Day)), "week") + " before"
+	case b%extra.Day == 0:
+		return plural(int(b/extra.Day), "day") + " before"
+	case b%time.Hour == 0:
+		return plural(int(b/time.Hour), "hour") + " before"
+	default:
+		return plural(int(b/time.Minute), "minute") + " before"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Message is what a notification of r says, after the name of its event.
+func Message(r Reminder) string {
+	switch {
+	case !r.HourSpecified:
+		return r.Start.Format("Monday 2 January")
+	case cal.SameDay(r.Start, r.At):
+		return "at " + r.Start.Format("3:04 PM")
+	default:
+		return r.Start.Format("Monday 2 January, 3:04 PM")
+	}
+}
@@ -0,0 +1,77 @@
+package remind
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func date(y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, time.UTC)
+}
+
+func TestDue(t *testing.T) {
+	standup := &cal.EventItem{
+		Name: "Standup", Time: date(2022, 3, 1, 9, 30), HourSpecified: true,
+		Recurs: true, Frequency: "daily",
+	}
+	review := &cal.EventItem{Name: "Review", Time: date(2022, 3, 3, 14, 0), HourSpecified: true}
+	offsite := &cal.EventItem{Name: "Offsite", Time: date(2022, 3, 4, 0, 0)}
+	quiet := &cal.EventItem{Name: "Quiet", Time: date(2022, 3, 3, 9, 0), HourSpecified: true}
+	es := []*cal.EventItem{standup, review, offsite, quiet}
+	tb := extra.Table{
+		standup: {Reminders: []time.Duration{10 * time.Minute}},
+		review:  {Reminders: []time.Duration{0, extra.Day}},
+		offsite: {Reminders: []time.Duration{extra.Day}},
+	}
+
+	cases := []struct {
+		name     string
+		from, to time.Time
+		want     []string
+	}{
+		{"none due", date(2022, 3, 3, 9, 0), date(2022, 3, 3, 9, 10), nil},
+		{"at the edge", date(2022, 3, 3, 9, 0), date(2022, 3, 3, 9, 20), []string{"Standup 2022-03-03 09:20"}},
+		{"after from only", date(2022, 3, 3, 9, 20), date(2022, 3, 3, 9, 30), nil},
+		{
+			"across a day",
+			date(2022, 3, 2, 12, 0), date(2022, 3, 3, 14, 0),
+			[]string{
+				"Review 2022-03-02 14:00",
+				"Offsite 2022-03-03 00:00",
+				"Standup 2022-03-03 09:20",
+				"Review 2022-03-03 14:00",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		rs := Due(es, tb, c.from, c.to)
+		if len(rs) != len(c.want) {
+			t.Errorf("%s: got %d reminders, want %d", c.name, len(rs), len(c.want))
+			continue
+		}
+		for i, r := range rs {
+			if got := r.Name + " " + r.At.Format("2006-01-02 15:04"); got != c.want[i] {
+				t.Errorf("%s: reminder %d is %q, want %q", c.name, i, got, c.want[i])
+			}
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	for b, want := range map[time.Duration]string{
+		0:                "at the start",
+		time.Minute:      "1 minute before",
+		10 * time.Minute: "10 minutes before",
+		2 * time.Hour:    "2 hours before",
+		extra.Day:        "1 day before",
+		14 * extra.Day:   "2 weeks before",
+	} {
+		if got := Describe(b); got != want {
+			t.Errorf("Describe(%s) = %q, want %q", b, got, want)
+		}
+	}
+}
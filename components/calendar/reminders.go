@@ -0,0 +1,75 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/remind"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+// EventTick asks the calendar to give the reminders which are due. The
+// client dispatches one every TickEvery.
+type EventTick struct{}
+type EventDismissToast struct{ Index int }
+
+// TickEvery is how often the client dispatches an EventTick.
+const TickEvery = 15 * time.Second
+
+// Toast is a reminder shown in the calendar, for when the browser doesn't
+// notify of it.
+type Toast struct {
+	Title, Message string
+}
+
+// Reminded is whether the last EventTick gave any reminders. A tick which
+// gave none changed nothing shown, nor anything worth keeping: the
+// RemindedUntil it moved on would, if lost, only look again over a time
+// with nothing due.
+func (s *State) Reminded() bool {
+	return s.reminded
+}
+
+// remind gives the reminders due since those last given, up to now, by
+// the browser's notifications if it may or else as toasts, and is whether
+// there were any.
+func (s *State) remind(now time.Time) bool {
+	if !s.loaded {
+		return false
+	}
+
+	from := s.RemindedUntil
+	if earliest := now.Add(-remind.Catchup); from.Before(earliest) {
+		from = earliest
+	}
+	if !from.Before(now) {
+		return false
+	}
+
+	due := remind.Due(s.shown(), s.Extras, from.In(s.location()), now.In(s.location()))
+	for _, r := range due {
+		title, message := r.Name, remind.Message(r)
+		if s.Notify == nil || !s.Notify(title, message) {
+			s.Toasts = append(s.Toasts, Toast{Title: title, Message: message})
+		}
+	}
+	s.RemindedUntil = now
+	return len(due) > 0
+}
+
+func toastsView(s *State) *browser.Node {
+	var views []*browser.Node
+	for i, t := range s.Toasts {
+		views = append(views, s.Theme.Card(
+			ui.HStack(
+				ui.VStack(
+					s.Theme.Text(t.Title).FontWeight("700"),
+					s.Theme.Text(t.Message),
+				),
+				ui.Spacer(),
+				s.Theme.Button("x").OnClickDispatch(EventDismissToast{i}).MarginLeftPX(10),
+			).AlignItemsCenter().PaddingPX(10),
+		).MarginBottomPX(5))
+	}
+	return ui.VStack(views...)
+}
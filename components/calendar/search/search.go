@@ -0,0 +1,113 @@
+// Package search finds the events of a calendar by what they say, and
+// filters them by how they recur and when they happen.
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+// Query is what to find events by. The zero Query finds nothing.
+type Query struct {
+	// Text is words which the name, details or tags of an event must each
+	// have, in any case.
+	Text string
+
+	RecurringOnly bool
+	HasDetails    bool
+
+	// Between, if set, is whether an event must happen between the days
+	// of From and To, inclusive.
+	Between  bool
+	From, To time.Time
+}
+
+// Empty is whether q finds nothing.
+func (q *Query) Empty() bool {
+	return strings.TrimSpace(q.Text) == "" && !q.RecurringOnly && !q.HasDetails && !q.Between
+}
+
+// Result is an event found, and its occurrences nearest the time it was
+// found at. An event which doesn't recur has one or the other.
+type Result struct {
+	*cal.EventItem
+	Next, Previous *extra.Occurrence
+}
+
+// Limit is the most results Find finds.
+const Limit = 50
+
+// Find is the results of q among es, which end as t has them, at now. The
+// results with an occurrence to come are first, the soonest first, then
+// those without, the latest first.
+func Find(q *Query, es []*cal.EventItem, t extra.Table, now time.Time) []*Result {
+	if q.Empty() {
+		return nil
+	}
+
+	words := strings.Fields(strings.ToLower(q.Text))
+	var rs []*Result
+	for _, e := range es {
+		if !matches(q, words, e, t) {
+			continue
+		}
+		r := &Result{EventItem: e}
+		if o, ok := t.Next(e, now); ok {
+			r.Next = &o
+		}
+		if o, ok := t.Previous(e, now); ok {
+			r.Previous = &o
+		}
+		rs = append(rs, r)
+	}
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		a, b := rs[i], rs[j]
+		switch {
+		case a.Next != nil && b.Next != nil:
+			return a.Next.Start.Before(b.Next.Start)
+		case a.Next != nil || b.Next != nil:
+			return a.Next != nil
+		case a.Previous != nil && b.Previous != nil:
+			return a.Previous.Start.After(b.Previous.Start)
+		default:
+			return a.Previous != nil
+		}
+	})
+
+	if len(rs) > Limit {
+		rs = rs[:Limit]
+	}
+	return rs
+}
+
+func matches(q *Query, words []string, e *cal.EventItem, t extra.Table) bool {
+	if q.RecurringOnly && !e.Recurs {
+		return false
+	}
+	if q.HasDetails && strings.TrimSpace(e.Details) == "" {
+		return false
+	}
+
+	name, details := strings.ToLower(e.Name), strings.ToLower(e.Details)
+	tags := strings.Join(t.Tags(e), " ")
+	for _, w := range words {
+		if !strings.Contains(name, w) && !strings.Contains(details, w) && !strings.Contains(tags, w) {
+			return false
+		}
+	}
+
+	if q.Between {
+		from := extra.Midnight(q.From)
+		to := extra.Midnight(q.To).AddDate(0, 0, 1)
+		if len(t.Occurrences([]*cal.EventItem{e}, from, to)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
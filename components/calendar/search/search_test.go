@@ -0,0 +1,66 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestFind(t *testing.T) {
+	standup := &cal.EventItem{
+		Name: "Standup", Details: "with the Platform team",
+		Time: date(2022, 1, 3), HourSpecified: true,
+		Recurs: true, Frequency: "weekly",
+	}
+	review := &cal.EventItem{Name: "Platform review", Time: date(2022, 4, 1), HourSpecified: true}
+	retro := &cal.EventItem{Name: "Platform retro", Time: date(2022, 2, 1), HourSpecified: true}
+	lunch := &cal.EventItem{Name: "Lunch", Time: date(2022, 3, 2), HourSpecified: true}
+	es := []*cal.EventItem{lunch, retro, review, standup}
+	tb := extra.Table{lunch: {Tags: []string{"team-social"}}}
+	now := date(2022, 3, 1)
+
+	cases := []struct {
+		name string
+		q    Query
+		want []*cal.EventItem
+	}{
+		{"empty", Query{}, nil},
+		{"words", Query{Text: "  PLATFORM "}, []*cal.EventItem{standup, review, retro}},
+		{"all the words", Query{Text: "platform team"}, []*cal.EventItem{standup}},
+		{"tags", Query{Text: "social"}, []*cal.EventItem{lunch}},
+		{"recurring", Query{RecurringOnly: true}, []*cal.EventItem{standup}},
+		{"details", Query{Text: "platform", HasDetails: true}, []*cal.EventItem{standup}},
+		{
+			"between",
+			Query{Text: "platform", Between: true, From: date(2022, 1, 20), To: date(2022, 2, 1)},
+			[]*cal.EventItem{standup, retro},
+		},
+	}
+
+	for _, c := range cases {
+		rs := Find(&c.q, es, tb, now)
+		if len(rs) != len(c.want) {
+			t.Errorf("%s: got %d results, want %d", c.name, len(rs), len(c.want))
+			continue
+		}
+		for i, r := range rs {
+			if r.EventItem != c.want[i] {
+				t.Errorf("%s: result %d is %q, want %q", c.name, i, r.Name, c.want[i].Name)
+			}
+		}
+	}
+
+	rs := Find(&Query{Text: "standup"}, es, tb, now)
+	if len(rs) != 1 || rs[0].Next == nil || rs[0].Previous == nil {
+		t.Fatalf("got %+v, want the standup with a next and previous occurrence", rs)
+	}
+	if !rs[0].Next.Start.Equal(date(2022, 3, 7)) || !rs[0].Previous.Start.Equal(date(2022, 2, 28)) {
+		t.Errorf("got next %v and previous %v", rs[0].Next.Start, rs[0].Previous.Start)
+	}
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/ui"
+)
+
+type State struct {
+	Theme      *ui.Theme        `json:"-"`
+	Time       *time.Time       `json:"-"` // for the zone of the results
+	EventItems []*cal.EventItem `json:"-"` // this is a pointer
+	Extras     extra.Table      `json:"-"`
+
+	Query
+}
+
+func (s *State) Handle(e browser.Event) {
+	switch e.(type) {
+	case EventToggleBetween:
+		s.Between = !s.Between
+		if s.Between && s.From.IsZero() {
+			s.From = *s.Time
+			s.To = s.Time.AddDate(0, 1, 0)
+		}
+	case EventClear, EventResultClick:
+		s.Query = Query{}
+	}
+}
+
+type EventToggleBetween struct{}
+type EventClear struct{}
+
+// EventResultClick is a click on the occurrence of an event At.
+type EventResultClick struct {
+	*cal.EventItem
+	At time.Time
+}
+
+func (s *State) SetTheme(t *ui.Theme) {
+	s.Theme = t
+}
+
+// Box is the box to search in.
+func Box(s *State) *browser.Node {
+	return s.Theme.TextInput(&s.Text).Placeholder("Search")
+}
+
+// View is the filters and the results of the search. It is empty until
+// there is something to search for.
+func View(s *State) *browser.Node {
+	if s.Empty() {
+		return ui.VStack()
+	}
+
+	var views []*browser.Node
+	for _, r := range Find(&s.Query, s.EventItems, s.Extras, time.Now().In(s.Time.Location())) {
+		views = append(views, resultView(s, r))
+	}
+
+	return s.Theme.Card(
+		ui.VStack(
+			ui.HStack(
+				s.Theme.Text("Recurring only"),
+				s.Theme.Toggle(&s.RecurringOnly),
+				s.Theme.Text("Has details").MarginLeftPX(10),
+				s.Theme.Toggle(&s.HasDetails),
+				ui.If(s.Between,
+					func() *browser.Node { return s.Theme.Button("Any time").OnClickDispatch(EventToggleBetween{}) },
+					func() *browser.Node { return s.Theme.Button("Between...").OnClickDispatch(EventToggleBetween{}) },
+				).MarginLeftPX(10),
+				ui.Spacer(),
+				s.Theme.Button("x").OnClickDispatch(EventClear{}),
+			).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap),
+			ui.OnlyIf(s.Between,
+				func() *browser.Node {
+					return ui.HStack(
+						s.Theme.DateInput(&s.From),
+						s.Theme.Text("and").PaddingPX(5),
+						s.Theme.DateInput(&s.To),
+					).AlignItemsCenter()
+				},
+			),
+			ui.If(len(views) > 0,
+				func() *browser.Node { return ui.VStack(views...) },
+				func() *browser.Node { return s.Theme.Text("No events found...").MarginTopPX(10) },
+			),
+			ui.OnlyIf(len(views) == Limit,
+				func() *browser.Node {
+					return s.Theme.Textf("Only the first %d events found are shown.", Limit).
+						Color("lightgray") // TODO: pull color from theme
+				},
+			),
+		).PaddingPX(10),
+	).
+		MinWidth(browser.Size{Value: 400, Unit: browser.UnitPX}).
+		MaxHeight(browser.Size{Value: 70, Unit: browser.UnitVH}).
+		OverflowScroll()
+}
+
+func resultView(s *State, r *Result) *browser.Node {
+	return ui.VStack(
+		ui.HStack(
+			s.Theme.Text(r.Name).FontSizeEM(1.1),
+			ui.Spacer(),
+			ui.OnlyIf(r.Recurs,
+				func() *browser.Node {
+					return s.Theme.Text(r.RecurString()).Color("gray") // TODO: pull color from theme
+				},
+			),
+		),
+		ui.HStack(
+			ui.OnlyIf(r.Previous != nil,
+				func() *browser.Node { return occurrenceView(s, "Last", r.Previous) },
+			),
+			ui.OnlyIf(r.Next != nil,
+				func() *browser.Node { return occurrenceView(s, "Next", r.Next) },
+			),
+		),
+	).
+		PaddingPX(5).
+		MarginTopPX(5).
+		BorderTop(browser.Border{
+			Color: "lightgray", // TODO: use theme?
+			Width: browser.Size{Value: 1, Unit: browser.UnitPX},
+			Type:  browser.BorderSolid,
+		})
+}
+
+// occurrenceView is a button to go to o.
+func occurrenceView(s *State, label string, o *extra.Occurrence) *browser.Node {
+	layout := "Mon 2 Jan 2006"
+	if o.HourSpecified {
+		layout = "Mon 2 Jan 2006, 3:04 PM"
+	}
+	return s.Theme.Button(label + ": " + o.Start.Format(layout)).
+		OnClickDispatch(EventResultClick{o.EventItem, o.Start}).
+		MarginRightPX(5)
+}
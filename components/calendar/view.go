@@ -3,20 +3,25 @@ package calendar
 import (
 	"bytes"
 	"fmt"
-	"log"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/agenda"
 	"github.com/nlandolfi/elos/web-client/components/calendar/day"
 	"github.com/nlandolfi/elos/web-client/components/calendar/editor"
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/elos/web-client/components/calendar/ics"
+	"github.com/nlandolfi/elos/web-client/components/calendar/importer"
 	"github.com/nlandolfi/elos/web-client/components/calendar/inspector"
 	"github.com/nlandolfi/elos/web-client/components/calendar/manager"
 	"github.com/nlandolfi/elos/web-client/components/calendar/month"
+	"github.com/nlandolfi/elos/web-client/components/calendar/search"
 	"github.com/nlandolfi/elos/web-client/components/calendar/week"
 	"github.com/nlandolfi/elos/web-client/components/calendar/year"
 	"github.com/nlandolfi/elos/web-client/components/selector"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/nlandolfi/spin/infra/key"
-	feditor "github.com/nlandolfi/spin/web/elos/components/editor"
 	"github.com/spinsrv/browser"
 	"github.com/spinsrv/browser/ui"
 )
@@ -27,78 +32,201 @@ type State struct {
 
 	Time time.Time
 
+	// Zone is the name of the time zone the calendar is seen in, as in
+	// "Europe/London"; empty for that of the browser.
+	Zone string
+
+	// TagColors is the colors of tags. An event with a tag which has a
+	// color is of the color of its first such tag, else of its calendar.
+	TagColors map[string]string
+
+	// RemindedUntil is when the reminders were last given up to; those due
+	// before it aren't given again. Toasts is the reminders given in the
+	// calendar which are yet to be dismissed.
+	RemindedUntil time.Time
+	Toasts        []Toast
+
+	// Notify, if set, notifies of a reminder with the browser, and is
+	// whether it could.
+	Notify func(title, message string) bool `json:"-"`
+
+	// QuickAdd is the phrase of events to add, as in "lunch tomorrow 12:30".
+	QuickAdd string
+
 	SelectorState selector.State
 
-	DayState     day.State
-	MonthState   month.State
-	WeekState    week.State
-	YearState    year.State
-	EditorState  editor.State
-	ManagerState manager.State
+	DayState      day.State
+	MonthState    month.State
+	WeekState     week.State
+	YearState     year.State
+	AgendaState   agenda.State
+	EditorState   editor.State
+	ManagerState  manager.State
+	ImporterState importer.State
+	SearchState   search.State
 	// TableState       table.State
 
 	// Inspector
 	InspectorState   inspector.State
 	InspectorVisible bool
 	InspectedEvent   *cal.EventItem
+	InspectedAt      time.Time // the occurrence of the event inspected
+
+	Scoping *scoping `json:"-"`
+
+	Moving *moving `json:"-"`
 
 	LastSelectedItem selector.Item
 
-	CalendarFile feditor.File
-	EventItems   []*cal.EventItem
+	// Calendars is those of the manager, as loaded, in its order.
+	Calendars []*Calendar
+	Status    string `json:"-"`
+
+	// EventItems is the events of all the Calendars, hidden or not, and
+	// Extras their extras. They are read from the Calendars' files.
+	EventItems []*cal.EventItem `json:"-"`
+	Extras     extra.Table      `json:"-"`
+
+	owners   map[*cal.EventItem]*Calendar // the calendar of each event
+	loaded   bool                         // the EventItems from the files
+	reminded bool                         // at the last EventTick
 }
 
-type EventEditEvent struct{ *cal.EventItem }
+// EventEditEvent asks to edit e, from its occurrence At, if it isn't zero.
+type EventEditEvent struct {
+	*cal.EventItem
+	At time.Time
+}
 type EventReloadEvents struct{}
 
 func (s *State) Handle(e browser.Event) {
 	switch e := e.(type) {
 	case week.EventEventClick:
-		s.InspectorVisible = true
-		s.InspectedEvent = e.EventItem
+		s.inspectEvent(e.EventItem, e.At)
+	case agenda.EventEventClick:
+		go browser.Dispatch(EventEditEvent{e.EventItem, e.At})
+	case search.EventResultClick:
+		s.Time = e.At
+		// the inspector is over the week and month
+		if k := s.SelectorState.SelectedKey; k != "week" && k != "month" {
+			s.SelectorState.SelectedKey = "month"
+			s.SelectorState.SelectedDisplay = "Month"
+		}
+		s.inspectEvent(e.EventItem, e.At)
 	case inspector.EventEditEvent:
-		go browser.Dispatch(EventEditEvent{s.InspectedEvent})
+		go browser.Dispatch(EventEditEvent{s.InspectedEvent, s.InspectedAt})
 	case manager.EventReloadCalendar, EventReloadEvents:
 		go s.reloadEvents()
 	case EventEditEvent:
-		s.editEvent(e.EventItem)
+		s.editEvent(e.EventItem, e.At)
 		go browser.Dispatch(selector.EventItemClick{
 			Target: &s.SelectorState,
-			Item:   *SelectorItems[4],
+			Item:   *selectorItem("editor"),
 		})
 	case editor.EventCreateEvent:
-		i := new(cal.EventItem)
-		s.EventItems = append(s.EventItems, i)
-		s.editEvent(i)
+		s.editEvent(nil, time.Time{})
 	case editor.EventCancel:
 		go browser.Dispatch(selector.EventItemClick{
 			Target: &s.SelectorState,
 			Item:   s.LastSelectedItem,
 		})
 	case editor.EventSave:
-		go s.save()
+		s.saveEdit()
 	case editor.EventBack:
 		go browser.Dispatch(selector.EventItemClick{
 			Target: &s.SelectorState,
 			Item:   s.LastSelectedItem,
 		})
-	case editor.EventDelete:
-		var es []*cal.EventItem
-		for _, i := range s.EventItems {
-			if e.ID != i.ID {
-				es = append(es, i)
+	case importer.EventImport:
+		c := s.calendar()
+		if c == nil {
+			s.ImporterState.Status = "no calendar to import to"
+			return
+		}
+		for _, en := range e.Entries {
+			if !en.Info.IsZero() {
+				i := en.Info
+				s.Extras[en.Event] = &i
 			}
+			s.owners[en.Event] = c
+			s.add(en.Event)
 		}
-		s.EventItems = es
+		s.ImporterState.Handle(importer.EventClear{})
+		s.ImporterState.Status = fmt.Sprintf("imported %d events", len(e.Entries))
 		go s.save()
+	case importer.EventExport:
+		var b bytes.Buffer
+		if err := ics.Write(&b, s.shown(), s.Extras); err != nil {
+			s.ImporterState.Status = err.Error()
+			return
+		}
+		s.ImporterState.Exported = b.String()
+	case editor.EventDelete:
+		s.deleteEdit()
+	case EventQuickAdd:
+		s.quickAdd()
+	case EventClearQuickAdd:
+		s.QuickAdd = ""
+	case EventTick:
+		s.reminded = s.remind(time.Now())
+		return
+	case EventDismissToast:
+		var ts []Toast
+		for j, t := range s.Toasts {
+			if j != e.Index {
+				ts = append(ts, t)
+			}
+		}
+		s.Toasts = ts
+		return
+	case manager.EventToggleHidden, manager.EventToggleReadOnly:
+		s.ManagerState.Handle(e)
+		s.syncCalendars()
+		s.Rewire(s.Theme, s.PrivateKey)
+		return
 	}
+	s.handleMove(e)
+	s.handleScope(e)
 	s.SelectorState.Handle(e)
 	s.DayState.Handle(e)
 	s.MonthState.Handle(e)
 	s.WeekState.Handle(e)
 	s.YearState.Handle(e)
+	s.AgendaState.Handle(e)
 	s.InspectorState.Handle(e)
 	s.EditorState.Handle(e)
+	s.ImporterState.Handle(e)
+	s.SearchState.Handle(e)
+	s.ManagerState.Handle(e)
+	s.syncCalendars() // for the colors
+
+	// the views keep to the zone of the time they show
+	s.Time = s.Time.In(s.location())
+}
+
+// zone is the time zone of Zone; the local one if there is none.
+func (s *State) zone() (*time.Location, error) {
+	if s.Zone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Zone)
+}
+
+// location is the time zone the calendar is seen in: that of Zone, or the
+// local one if it doesn't load, which zoneView says.
+func (s *State) location() *time.Location {
+	loc, err := s.zone()
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func zoneView(s *State) *browser.Node {
+	if _, err := s.zone(); err != nil {
+		return s.Theme.Textf("unknown time zone %q", s.Zone).MarginLeftPX(5)
+	}
+	return s.Theme.Text(s.Time.Format("MST")).MarginLeftPX(5)
 }
 
 func (s *State) Rewire(th *ui.Theme, k **key.PrivateKey) {
@@ -113,34 +241,79 @@ func (s *State) Rewire(th *ui.Theme, k **key.PrivateKey) {
 	if s.Time.IsZero() {
 		s.Time = time.Now()
 	}
+	s.Time = s.Time.In(s.location())
+
+	s.ManagerState.Migrate()
+	if s.TagColors == nil {
+		s.TagColors = make(map[string]string)
+	}
+	if !s.loaded {
+		s.reloadEventItems() // which rewires
+		return
+	}
+
+	es := s.shown()
 
-	//	s.TableState.EventItems = s.EventItems
+	//	s.TableState.EventItems = es
 
 	s.DayState.Time = &s.Time
-	s.DayState.EventItems = s.EventItems
-	s.DayState.DispatchEditEvent = func(e *cal.EventItem) {
-		go browser.Dispatch(EventEditEvent{e})
+	s.DayState.EventItems = es
+	s.DayState.Extras = s.Extras
+	s.DayState.Color = s.color
+	s.DayState.DispatchEditEvent = func(e *cal.EventItem, at time.Time) {
+		go browser.Dispatch(EventEditEvent{e, at})
 	}
 
 	s.WeekState.Time = &s.Time
-	s.WeekState.EventItems = s.EventItems
+	s.WeekState.EventItems = es
+	s.WeekState.Extras = s.Extras
+	s.WeekState.Color = s.color
 	s.WeekState.SelectedKey = &s.SelectorState.SelectedKey
+	s.WeekState.MoveEvent = s.moveEvent
 
 	s.MonthState.Time = &s.Time
-	s.MonthState.EventItems = s.EventItems
+	s.MonthState.EventItems = es
+	s.MonthState.Extras = s.Extras
+	s.MonthState.Color = s.color
 	s.MonthState.SelectedKey = &s.SelectorState.SelectedKey
 	s.MonthState.InspectEvent = s.inspectEvent
+	s.MonthState.MoveEvent = s.moveEvent
 
 	s.YearState.Time = &s.Time
-	s.YearState.EventItems = s.EventItems
+	s.YearState.EventItems = es
+	s.YearState.Extras = s.Extras
 	s.YearState.SelectedKey = &s.SelectorState.SelectedKey
+	s.YearState.Color = s.color
+	s.YearState.Tags = s.tags()
+
+	s.AgendaState.Time = &s.Time
+	s.AgendaState.EventItems = es
+	s.AgendaState.Extras = s.Extras
+	s.AgendaState.Color = s.color
+	if s.AgendaState.Days == 0 {
+		s.AgendaState.Days = agenda.DefaultDays
+	}
+
+	s.SearchState.Time = &s.Time
+	s.SearchState.EventItems = es
+	s.SearchState.Extras = s.Extras
 
 	s.InspectorState.Visible = &s.InspectorVisible
 	s.InspectorState.EventItem = &s.InspectedEvent
+	s.InspectorState.At = &s.InspectedAt
+	s.InspectorState.Extras = s.Extras
 
 	s.EditorState.Rewire(th, k)
 	s.EditorState.Time = &s.Time
+	s.EditorState.Extras = s.Extras
+	s.EditorState.Zones = s.zones()
+	s.EditorState.Calendars = nil
+	for _, c := range s.Calendars {
+		s.EditorState.Calendars = append(s.EditorState.Calendars, c.CalendarReference)
+	}
 	s.EditorState.SelectedKey = &s.SelectorState.SelectedKey
+	s.EditorState.Tags = s.YearState.Tags
+	s.EditorState.TagColors = s.TagColors
 }
 
 func (s *State) SetTheme(th *ui.Theme) {
@@ -151,8 +324,11 @@ func (s *State) SetTheme(th *ui.Theme) {
 	s.WeekState.SetTheme(th)
 	s.DayState.SetTheme(th)
 	s.YearState.SetTheme(th)
+	s.AgendaState.SetTheme(th)
 	s.InspectorState.SetTheme(th)
 	s.ManagerState.Theme = th
+	s.ImporterState.Theme = th
+	s.SearchState.SetTheme(th)
 }
 
 func (s *State) SetPrivateKey(k **key.PrivateKey) {
@@ -176,6 +352,10 @@ var SelectorItems = []*selector.Item{
 		Key:     "year",
 		Display: "Year",
 	},
+	&selector.Item{
+		Key:     "agenda",
+		Display: "Agenda",
+	},
 	//	&selector.Item{
 	//		Key:     "table",
 	//		Display: "Table",
@@ -188,6 +368,20 @@ var SelectorItems = []*selector.Item{
 		Key:     "manager",
 		Display: "Manager",
 	},
+	&selector.Item{
+		Key:     "import",
+		Display: "iCalendar",
+	},
+}
+
+// selectorItem is the item of SelectorItems with key.
+func selectorItem(key string) *selector.Item {
+	for _, i := range SelectorItems {
+		if i.Key == key {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("unknown selector item: %v", key))
 }
 
 func View(s *State) *browser.Node {
@@ -196,13 +390,39 @@ func View(s *State) *browser.Node {
 			ui.HStack(
 				selector.View(&s.SelectorState, SelectorItems),
 				s.Theme.Button("Reload").OnClickDispatch(EventReloadEvents{}).PaddingPX(9).PaddingBottomPX(3),
-				s.Theme.Text(s.CalendarFile.Status),
+				s.Theme.Text(s.Status),
+				ui.Spacer(),
+				quickAddBox(s),
+				search.Box(&s.SearchState).MarginLeftPX(5),
+				s.Theme.TextInput(&s.Zone).Placeholder("Time zone").MarginLeftPX(5),
+				zoneView(s),
 			).AlignItemsCenter(),
 			view(s).PaddingPX(10),
 		),
 		ui.OnlyIf(s.InspectorVisible && (s.SelectorState.SelectedKey == "week" || s.SelectorState.SelectedKey == "month"),
 			func() *browser.Node { return inspector.View(&s.InspectorState) },
 		).PositionAbsolute(), // for positioning
+		ui.OnlyIf(!s.SearchState.Empty(),
+			func() *browser.Node {
+				return search.View(&s.SearchState).PositionAbsolute().LeftPX(100).TopPX(50)
+			},
+		),
+		ui.OnlyIf(strings.TrimSpace(s.QuickAdd) != "",
+			func() *browser.Node {
+				return quickAddView(s).PositionAbsolute().LeftPX(100).TopPX(50)
+			},
+		),
+		ui.OnlyIf(len(s.Toasts) > 0,
+			func() *browser.Node {
+				return toastsView(s).PositionAbsolute().LeftPX(520).TopPX(50) // beside the search
+			},
+		),
+		ui.OnlyIf(s.Moving != nil,
+			func() *browser.Node { return moveView(s) },
+		),
+		ui.OnlyIf(s.Scoping != nil,
+			func() *browser.Node { return scopeView(s) },
+		),
 	).PositionRelative() // relative for the inspector
 }
 
@@ -218,109 +438,56 @@ func view(s *State) *browser.Node {
 		return month.View(&s.MonthState)
 	case "year":
 		return year.View(&s.YearState)
+	case "agenda":
+		return agenda.View(&s.AgendaState)
 	case "editor":
 		return editor.View(&s.EditorState)
 	case "manager":
 		return manager.View(&s.ManagerState)
+	case "import":
+		return importer.View(&s.ImporterState)
 	default:
 		panic(fmt.Sprintf("unknown selected state: %v", s.SelectorState.SelectedKey))
 	}
 }
 
-func (s *State) inspectEvent(e *cal.EventItem) {
+func (s *State) inspectEvent(e *cal.EventItem, at time.Time) {
 	s.InspectorVisible = true
 	s.InspectedEvent = e
+	s.InspectedAt = at
 	s.InspectorState.EventItem = &s.InspectedEvent
-}
-
-func (s *State) save() {
-	log.Print("writing events")
-	var b bytes.Buffer
-
-	if err := cal.WriteEvents(&b, s.EventItems); err != nil {
-		s.CalendarFile.Status = err.Error() // TODO: don't use this status field
-	}
-	log.Print("and saving")
-
-	s.CalendarFile.Text = b.String()
-	s.CalendarFile.Save()
-	s.reloadEventItems()
-	go browser.Dispatch(editor.EventSaved{})
+	s.InspectorState.Extras = s.Extras
 }
 
 func (s *State) Reload() {
 	s.reloadEvents()
 }
 
-func (s *State) reloadEventItems() {
-	b := bytes.NewBufferString(s.CalendarFile.Text)
-
-	es, err := cal.ParseEvents(b)
-	if err != nil {
-		s.CalendarFile.Status = err.Error()
-		return
+// zones is the names of the time zones the editor suggests: that the
+// calendar is seen in, and those of its events.
+func (s *State) zones() []string {
+	var zs []string
+	seen := map[string]bool{"": true, s.Zone: true}
+	for _, e := range s.EventItems {
+		if i, ok := s.Extras[e]; ok && !seen[i.Zone] {
+			seen[i.Zone] = true
+			zs = append(zs, i.Zone)
+		}
 	}
-	for i, e := range es {
-		e.ID = fmt.Sprintf("%d-%d", s.CalendarFile.ShadowSequence, i)
+	sort.Strings(zs)
+
+	if s.Zone != "" {
+		zs = append([]string{s.Zone}, zs...)
 	}
-	s.EventItems = es
-	s.Rewire(s.Theme, s.PrivateKey)
+	return zs
 }
 
-func (s *State) reloadEvents() {
-	s.InspectorVisible = false
-	s.InspectedEvent = nil
-	s.EditorState.Event = nil
-
-	s.CalendarFile.PrivateKey = s.PrivateKey
-	s.CalendarFile.Citizen = s.ManagerState.Calendar.Citizen
-	s.CalendarFile.Path = s.ManagerState.Calendar.Path
-	s.CalendarFile.Reload()
-
-	s.reloadEventItems()
-
-	/*
-		s.Error = "loading..."
-		s.CalendarFile.Reload()
-		go browser.Dispatch(nil)
-		defer func() { go browser.Dispatch(nil) }()
-
-		s.CalendarFile.PrivateKey = s.PrivateKey
-		s.CalendarFile.Citizen = s.ManagerState.Calendar.Citizen
-
-		k := *s.PrivateKey
-		s.Error = "loading"
-		go browser.Dispatch(nil)
-		defer func() { go browser.Dispatch(nil) }()
-		c := new(cal.CalServerHTTPClient)
-		p := fs.Path(s.ManagerState.Calendar.Path)
-		if s.Path != "" {
-			p = fs.Path(s.Path)
+// event is the event with id, if there is one.
+func (s *State) event(id string) *cal.EventItem {
+	for _, e := range s.EventItems {
+		if e.ID == id {
+			return e
 		}
-
-		resp := c.All(&cal.CalAllRequest{
-			Public: string(k.Name), Private: k.Private,
-			Citizen: ctzn.Name(s.ManagerState.Calendar.Citizen), Path: p,
-		})
-
-		if resp.Error != "" {
-			s.Error = resp.Error
-			return
-		}
-
-		sort.Sort(cal.ByTime{resp.Events})
-
-		s.EventItems = resp.Events
-		s.Error = ""
-		// hack to rewire events
-		s.Rewire(s.Theme, s.PrivateKey) // TODO
-	*/
-}
-
-func (s *State) editEvent(e *cal.EventItem) {
-	s.LastSelectedItem = selector.Item{
-		Key:     s.SelectorState.SelectedKey,
-		Display: s.SelectorState.SelectedDisplay,
 	}
-	s.EditorState.Event = e
+	return nil
 }
@@ -0,0 +1,95 @@
+package week
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+)
+
+// defaultDuration is how long an event without an end looks in the grid.
+const defaultDuration = time.Hour
+
+// block is where the occurrence of a timed event sits in a day's column of
+// the grid.
+type block struct {
+	extra.Occurrence
+	start, end time.Time // clipped to the day
+
+	// the event sits in column col of cols; cols is the same for
+	// all the events that overlap one another
+	col, cols int
+}
+
+// layout places the timed events of os, which are on day. Events that
+// overlap are put side by side: each goes in the leftmost
+// column free at its start, and a group of overlapping events shares the
+// number of columns it needs.
+func layout(day time.Time, os []extra.Occurrence) []*block {
+	day = extra.Midnight(day)
+	next := day.AddDate(0, 0, 1)
+
+	var bs []*block
+	for _, o := range os {
+		if !o.HourSpecified {
+			continue
+		}
+		start, end := o.Start, o.End
+		if !end.After(start) {
+			end = start.Add(defaultDuration)
+		}
+		if start.Before(day) {
+			start = day
+		}
+		if end.After(next) {
+			end = next
+		}
+		bs = append(bs, &block{Occurrence: o, start: start, end: end})
+	}
+
+	// earlier first, and longer first among events which start together
+	sort.SliceStable(bs, func(i, j int) bool {
+		if !bs[i].start.Equal(bs[j].start) {
+			return bs[i].start.Before(bs[j].start)
+		}
+		return bs[i].end.After(bs[j].end)
+	})
+
+	var (
+		group []*block
+		ends  []time.Time // of the last event in each column of the group
+		last  time.Time   // the group ends
+	)
+	flush := func() {
+		for _, b := range group {
+			b.cols = len(ends)
+		}
+		group, ends = nil, nil
+	}
+	for _, b := range bs {
+		if len(group) > 0 && !b.start.Before(last) {
+			flush()
+		}
+
+		b.col = len(ends)
+		for i, end := range ends {
+			if !b.start.Before(end) {
+				b.col = i
+				break
+			}
+		}
+		if b.col == len(ends) {
+			ends = append(ends, b.end)
+		} else {
+			ends[b.col] = b.end
+		}
+
+		if len(group) == 0 || b.end.After(last) {
+			last = b.end
+		}
+		group = append(group, b)
+	}
+	flush()
+
+	return bs
+}
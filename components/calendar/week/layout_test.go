@@ -0,0 +1,93 @@
+package week
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
+	"github.com/nlandolfi/spin/apps/cal"
+)
+
+var testDay = time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// at is the time h:m on testDay; hours past 23, or before 0, are of the days
+// after or before it.
+func at(h, m int) time.Time {
+	return testDay.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+}
+
+func timed(name string, start, end time.Time) extra.Occurrence {
+	return extra.Occurrence{
+		EventItem: &cal.EventItem{Name: name, Time: start, HourSpecified: true},
+		Start:     start,
+		End:       end,
+	}
+}
+
+// describe is each block as "name col/cols start-end", in order.
+func describe(bs []*block) string {
+	var ds []string
+	for _, b := range bs {
+		ds = append(ds, fmt.Sprintf("%s %d/%d %s-%s", b.Name, b.col, b.cols,
+			b.start.Format("Jan2 15:04"), b.end.Format("Jan2 15:04")))
+	}
+	return strings.Join(ds, ", ")
+}
+
+func TestLayout(t *testing.T) {
+	cases := []struct {
+		name string
+		os   []extra.Occurrence
+		want string
+	}{
+		{
+			"overlapping",
+			[]extra.Occurrence{timed("a", at(9, 0), at(11, 0)), timed("b", at(10, 0), at(12, 0))},
+			"a 0/2 Mar1 09:00-Mar1 11:00, b 1/2 Mar1 10:00-Mar1 12:00",
+		},
+		{
+			"chained",
+			[]extra.Occurrence{timed("c", at(11, 0), at(12, 0)), timed("a", at(9, 0), at(10, 0)), timed("b", at(10, 0), at(11, 0))},
+			"a 0/1 Mar1 09:00-Mar1 10:00, b 0/1 Mar1 10:00-Mar1 11:00, c 0/1 Mar1 11:00-Mar1 12:00",
+		},
+		{
+			"starting together",
+			[]extra.Occurrence{timed("short", at(9, 0), at(10, 0)), timed("long", at(9, 0), at(11, 0))},
+			"long 0/2 Mar1 09:00-Mar1 11:00, short 1/2 Mar1 09:00-Mar1 10:00",
+		},
+		{
+			"a column freed",
+			[]extra.Occurrence{timed("a", at(9, 0), at(12, 0)), timed("b", at(9, 30), at(10, 0)), timed("c", at(10, 30), at(11, 0))},
+			"a 0/2 Mar1 09:00-Mar1 12:00, b 1/2 Mar1 09:30-Mar1 10:00, c 1/2 Mar1 10:30-Mar1 11:00",
+		},
+		{
+			"separate groups",
+			[]extra.Occurrence{timed("a", at(9, 0), at(10, 0)), timed("b", at(9, 30), at(10, 30)), timed("c", at(11, 0), at(12, 0))},
+			"a 0/2 Mar1 09:00-Mar1 10:00, b 1/2 Mar1 09:30-Mar1 10:30, c 0/1 Mar1 11:00-Mar1 12:00",
+		},
+		{
+			"clipped to the day",
+			[]extra.Occurrence{
+				timed("late", at(23, 0), at(26, 0)),
+				timed("overnight", at(-2, 0), at(1, 0)),
+			},
+			"overnight 0/1 Mar1 00:00-Mar1 01:00, late 0/1 Mar1 23:00-Mar2 00:00",
+		},
+		{
+			"without an end or an hour",
+			[]extra.Occurrence{
+				timed("open", at(14, 0), time.Time{}),
+				{EventItem: &cal.EventItem{Name: "all day", Time: testDay}, Start: testDay, End: testDay},
+			},
+			"open 0/1 Mar1 14:00-Mar1 15:00",
+		},
+	}
+
+	for _, c := range cases {
+		if got := describe(layout(at(12, 0), c.os)); got != c.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", c.name, got, c.want)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package week
 
 import (
-	"log"
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/elos/web-client/components/calendar/ltr"
 	"github.com/nlandolfi/spin/apps/cal"
 	"github.com/spinsrv/browser"
+	"github.com/spinsrv/browser/dom"
 	"github.com/spinsrv/browser/ui"
 )
 
@@ -15,9 +16,23 @@ type State struct {
 	Time        *time.Time `json:"-"`
 	SelectedKey *string
 	EventItems  []*cal.EventItem `json:"-"` // this is a pointer
+	Extras      extra.Table      `json:"-"`
 
 	hoveredDay     time.Time
 	hoveredEventID string
+	dragging       *drag
+
+	// MoveEvent moves the occurrence of e at start by days.
+	MoveEvent func(e *cal.EventItem, start time.Time, days int) `json:"-"`
+	// Color, if set, is the color of an event.
+	Color func(e *cal.EventItem) string `json:"-"`
+}
+
+// drag is an event being dragged to another day.
+type drag struct {
+	*cal.EventItem
+	start time.Time // of the occurrence dragged
+	from  time.Time // the day it was dragged from
 }
 
 func (s *State) SetTheme(t *ui.Theme) {
@@ -26,6 +41,7 @@ func (s *State) SetTheme(t *ui.Theme) {
 
 type EventEventClick struct {
 	*cal.EventItem
+	At time.Time // the occurrence clicked
 }
 
 func (s *State) Handle(e browser.Event) {
@@ -51,6 +67,16 @@ func (s *State) Handle(e browser.Event) {
 		if s.hoveredEventID == e.ID {
 			s.hoveredEventID = ""
 		}
+	case EventDragStart:
+		s.dragging = &drag{e.EventItem, e.Start, e.From}
+	case EventDrop:
+		d := s.dragging
+		s.dragging = nil
+		// dropped where it started, it was a click
+		if d == nil || cal.SameDay(d.from, e.Time) || s.MoveEvent == nil {
+			return
+		}
+		s.MoveEvent(d.EventItem, d.start, extra.Days(d.from, e.Time))
 	}
 }
 
@@ -63,6 +89,11 @@ type EventIncrementWeek struct{}
 type EventEventHoverStart struct{ ID string }
 type EventEventHoverLeave struct{ ID string }
 type EventInspectEvent struct{ *cal.EventItem }
+type EventDragStart struct {
+	*cal.EventItem
+	Start, From time.Time
+}
+type EventDrop struct{ time.Time }
 
 func View(s *State) *browser.Node {
 	return view(s)
@@ -82,133 +113,209 @@ func view(s *State) *browser.Node {
 				OnClickNext:  browser.Dispatcher(EventIncrementWeek{}),
 			}),
 		).AlignItemsCenter(),
-		simplegrid(s),
+		grid(s),
 	).FlexGrow("1")
 }
 
-func simplegrid(s *State) *browser.Node {
+// hourHeight is the height, in pixels, of an hour in the grid.
+const hourHeight = 48
 
-	dayCols := make([]*browser.Node, 7)
+// gutterWidth is the width, in pixels, of the column of hour labels.
+const gutterWidth = 60
 
-	for i, day := range cal.DaysInWeekOf(*s.Time) {
-		lcache := day.String()
-		var eviews []*browser.Node = []*browser.Node{
-			ui.HStack(
-				s.Theme.Button(day.Format("Mon 2")).
-					OnClickCached(lcache, browser.Dispatcher(EventDayClick{day})),
-			).BorderBottom(border(s.Theme)).JustifyContentCenter(),
-		}
-		for _, e := range s.EventItems {
-			if e.ShouldDisplayOnDay(day) {
-				eviews = append(eviews, lineView(s, e))
-			}
-		}
+func grid(s *State) *browser.Node {
+	days := cal.DaysInWeekOf(*s.Time)
+
+	heads := []*browser.Node{gutter()}
+	alldays := []*browser.Node{
+		gutter(
+			s.Theme.Text("all-day").TextAlignRight().PaddingRightPX(5),
+		),
+	}
+	cols := []*browser.Node{hours(s)}
+
+	from := extra.Midnight(days[0])
+	byDay := extra.ByDay(
+		s.Extras.Occurrences(s.EventItems, from, from.AddDate(0, 0, len(days))),
+		from, len(days),
+	)
 
-		dayCols[i] = ui.VStack(eviews...).
-			FlexGrow("1").
-			FlexBasis("0px").
-			MarginLeftPX(1).
-			MarginRightPX(1)
+	for i, day := range days {
+		day = extra.Midnight(day)
+		heads = append(heads, dayHead(s, day))
+		alldays = append(alldays, allDay(s, day, byDay[i], i == len(days)-1))
+		cols = append(cols, dayColumn(s, day, byDay[i], i == len(days)-1))
 	}
 
-	return ui.HStack(dayCols...)
+	return ui.VStack(
+		ui.HStack(heads...),
+		ui.HStack(alldays...).
+			Color("gray").
+			FontSizePX(10).
+			BorderBottom(border(s.Theme)),
+		ui.HStack(cols...).
+			MaxHeight(browser.Size{Value: 70, Unit: browser.UnitVH}).
+			OverflowScroll(),
+	).FlexGrow("1")
 }
 
-func grid(s *State) *browser.Node {
-	var days []time.Time
+// gutter is a cell of the column of hour labels, on the left.
+func gutter(children ...*browser.Node) *browser.Node {
+	return ui.VStack(children...).
+		MinWidth(browser.Size{Value: gutterWidth, Unit: browser.UnitPX}).
+		MaxWidth(browser.Size{Value: gutterWidth, Unit: browser.UnitPX})
+}
 
-	for i := 0; i < int(s.Time.Weekday()); i++ {
-		log.Printf("%d before", i)
-		days = append(days, s.Time.Add(-time.Duration(int(s.Time.Weekday())-i)*24*time.Hour))
-	}
-	days = append(days, *s.Time)
-	for i := s.Time.Weekday() + 1; i <= time.Saturday; i++ {
-		log.Printf("%d after", i)
-		days = append(days, s.Time.Add(time.Duration(i)*24*time.Hour))
-	}
+func dayHead(s *State, day time.Time) *browser.Node {
+	lcache := day.String()
+	return ui.HStack(
+		s.Theme.Button(day.Format("Mon 2")).
+			OnlyIf(cal.SameDay(day, time.Now().In(day.Location())), // TODO get time from state
+				func(n *browser.Node) *browser.Node {
+					return n.Color("red") // TODO use theme
+				}).
+			OnClickCached(lcache, browser.Dispatcher(EventDayClick{day})),
+	).
+		JustifyContentCenter().
+		FlexGrow("1").
+		FlexBasis("0px").
+		MinWidth(browser.Size{Value: 60, Unit: browser.UnitPX})
+}
 
-	var cols []*browser.Node
-
-	cols = append(cols, ui.VStack(
-		s.Theme.Text("").FlexGrow("1"),
-		s.Theme.Text("all-day").FlexGrow("1"),
-		s.Theme.Text("12:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 1:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 2:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 3:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 4:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 5:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 6:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 7:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 8:00 AM").FlexGrow("1"),
-		s.Theme.Text(" 9:00 AM").FlexGrow("1"),
-		s.Theme.Text("10:00 AM").FlexGrow("1"),
-		s.Theme.Text("11:00 AM").FlexGrow("1"),
-		s.Theme.Text("12:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 1:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 2:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 3:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 4:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 5:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 6:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 7:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 8:00 PM").FlexGrow("1"),
-		s.Theme.Text(" 9:00 PM").FlexGrow("1"),
-		s.Theme.Text("10:00 PM").FlexGrow("1"),
-		s.Theme.Text("11:00 PM").FlexGrow("1").Color("lightgray"),
-	).Color("lightgray").FontSize(browser.Size{Value: 10, Unit: browser.UnitPX}))
-
-	for j, _ := range days {
-		var rows []*browser.Node
-
-		/*
-			rows = append(rows, ui.HStack(
-				s.Theme.Button(day.Format("Mon 2")).
-					OnClick(func(d time.Time) func(browser.Dispatcher(EventDayClick{day})) {
-						return func(_ dom.Event) {
-							*s.Time = d
-							*s.SelectedKey = "day"
-						}
-					}(day)),
-			).JustifyContentCenter(),
-			)
-		*/
-
-		rows = append(rows, gridSquare(s, -1, true, j == 0, false, false))
-
-		for i := 0; i < 24; i++ {
-			rows = append(rows, gridSquare(s, i, false, j == 0, i == 23, j == 6))
+// allDay is the events without an hour of os, which are on day.
+func allDay(s *State, day time.Time, os []extra.Occurrence, colEnd bool) *browser.Node {
+	var views []*browser.Node
+	for _, o := range os {
+		if !o.HourSpecified {
+			views = append(views, lineView(s, o.EventItem, o.Start).OnMouseDown(dragStart(o.EventItem, o.Start, day)))
 		}
+	}
 
-		cols = append(cols, ui.VStack(rows...).FlexGrow("1"))
+	return ui.VStack(views...).
+		FlexGrow("1").
+		FlexBasis("0px").
+		MinWidth(browser.Size{Value: 60, Unit: browser.UnitPX}).
+		MinHeight(browser.Size{Value: 2, Unit: browser.UnitEM}).
+		PaddingBottomPX(2).
+		OnlyIf(s.dragging != nil && cal.SameDay(s.hoveredDay, day), func(n *browser.Node) *browser.Node {
+			return n.Background("#f4f4f4") // TODO use theme
+		}).
+		OnMouseOverCached(day.String(), browser.Dispatcher(EventDayHoverStart{day})).
+		OnMouseUp(browser.Dispatcher(EventDrop{day})).
+		BorderLeft(border(s.Theme)).
+		OnlyIf(colEnd, func(n *browser.Node) *browser.Node {
+			return n.BorderRight(border(s.Theme))
+		})
+}
+
+// hours is the column of hour labels.
+func hours(s *State) *browser.Node {
+	labels := make([]*browser.Node, 24)
+	for h := range labels {
+		labels[h] = s.Theme.Text(time.Date(0, 1, 1, h, 0, 0, 0, time.UTC).Format("3 PM")).
+			TextAlignRight().
+			PaddingRightPX(5).
+			HeightPX(hourHeight)
 	}
+	return gutter(labels...).
+		Color("gray").
+		FontSizePX(10)
+}
 
-	//	log.Printf("%s is a %s; month has %d days", day, day.Weekday(), len(days))
+// dayColumn is the hour grid of day, with the timed events of os, which
+// are on day, laid over it.
+func dayColumn(s *State, day time.Time, os []extra.Occurrence, colEnd bool) *browser.Node {
+	var views []*browser.Node
 
-	//	return ui.VStack(s.Theme.Text("NONSENSE"))
+	for h := 0; h < 24; h++ {
+		views = append(views, ui.VStack().
+			PositionAbsolute().
+			TopPX(float64(h*hourHeight)).
+			LeftPX(0).
+			Width(browser.Size{Value: 100, Unit: browser.UnitPG}).
+			HeightPX(hourHeight).
+			BorderTop(border(s.Theme)),
+		)
+	}
 
-	return ui.HStack(cols...).FlexGrow("1")
-}
+	for _, b := range layout(day, os) {
+		views = append(views, blockView(s, day, b))
+	}
 
-func gridSquare(s *State, hour int, rowStart, colStart, rowEnd, colEnd bool) *browser.Node {
-	return ui.HStack(
-		s.Theme.Textf("Hour %d", hour),
-	).
-		PaddingPX(10).
+	if now := time.Now().In(day.Location()); cal.SameDay(day, now) { // TODO get time from state
+		views = append(views, ui.VStack().
+			PositionAbsolute().
+			TopPX(offset(day, now)).
+			LeftPX(0).
+			Width(browser.Size{Value: 100, Unit: browser.UnitPG}).
+			HeightPX(2).
+			Background("red"), // TODO use theme
+		)
+	}
+
+	return ui.VStack(views...).
+		PositionRelative(). // for positioning the blocks
 		FlexGrow("1").
 		FlexBasis("0px").
 		MinWidth(browser.Size{Value: 60, Unit: browser.UnitPX}).
-		MinHeight(browser.Size{Value: 100, Unit: browser.UnitPX}).
-		BorderTop(border(s.Theme)).
-		BorderLeft(border(s.Theme)).
-		OnlyIf(rowEnd, func(n *browser.Node) *browser.Node {
-			return n.BorderBottom(border(s.Theme))
+		MinHeight(browser.Size{Value: 24 * hourHeight, Unit: browser.UnitPX}).
+		OnlyIf(s.dragging != nil && cal.SameDay(s.hoveredDay, day), func(n *browser.Node) *browser.Node {
+			return n.Background("#f4f4f4") // TODO use theme
 		}).
+		OnMouseOverCached(day.String(), browser.Dispatcher(EventDayHoverStart{day})).
+		OnMouseUp(browser.Dispatcher(EventDrop{day})).
+		BorderLeft(border(s.Theme)).
+		BorderBottom(border(s.Theme)).
 		OnlyIf(colEnd, func(n *browser.Node) *browser.Node {
 			return n.BorderRight(border(s.Theme))
 		})
 }
 
+// offset is how far down day's column t is, in pixels.
+func offset(day, t time.Time) float64 {
+	return t.Sub(day).Hours() * hourHeight
+}
+
+func blockView(s *State, day time.Time, b *block) *browser.Node {
+	width := 100 / float64(b.cols)
+	height := offset(day, b.end) - offset(day, b.start)
+
+	return ui.VStack(
+		s.Theme.Text(b.Name).OverflowHidden().
+			FontSizeEM(0.8).
+			Color("black"),
+		s.Theme.Text(b.Start.Format("3:04 PM")).
+			FontSizeEM(0.7).
+			Color("black"),
+	).
+		PositionAbsolute().
+		TopPX(offset(day, b.start)).
+		HeightPX(height-2).
+		Left(browser.Size{Value: width * float64(b.col), Unit: browser.UnitPG}).
+		Width(browser.Size{Value: width, Unit: browser.UnitPG}).
+		OverflowHidden().
+		PaddingPX(2).
+		Background(color(s, b.EventItem)).
+		BorderRadiusPX(3).
+		BorderLeft(border(s.Theme)).
+		OnMouseOverCached(b.ID, browser.Dispatcher(EventEventHoverStart{b.ID})).
+		OnMouseOutCached(b.ID, browser.Dispatcher(EventEventHoverLeave{b.ID})).
+		OnlyIf(b.ID == s.hoveredEventID, func(n *browser.Node) *browser.Node {
+			return n.BoxShadow(itemLiftedShadow)
+		}).
+		OnMouseDown(dragStart(b.EventItem, b.Start, day)).
+		OnClickCached(b.ID+b.Start.String(), browser.Dispatcher(EventEventClick{b.EventItem, b.Start})).
+		Pointer()
+}
+
+// dragStart starts dragging the occurrence of e at start from day.
+func dragStart(e *cal.EventItem, start, day time.Time) func(dom.Event) {
+	return func(ev dom.Event) {
+		ev.PreventDefault() // don't select text
+		go browser.Dispatch(EventDragStart{EventItem: e, Start: start, From: day})
+	}
+}
+
 func border(th *ui.Theme) browser.Border {
 	return browser.Border{
 		Color: "lightgray", // TODO: use theme?
@@ -217,7 +324,7 @@ func border(th *ui.Theme) browser.Border {
 	}
 }
 
-func lineView(s *State, e *cal.EventItem) *browser.Node {
+func lineView(s *State, e *cal.EventItem, start time.Time) *browser.Node {
 	return ui.VStack(
 		s.Theme.Text(e.Name).OverflowHidden().
 			FontSizeEM(1).
@@ -229,10 +336,26 @@ func lineView(s *State, e *cal.EventItem) *browser.Node {
 			}),
 	).
 		PaddingPX(2).
-		Background("lightgray").
+		Background(color(s, e)).
 		MarginTopPX(2).
 		BorderRadiusPX(3).
-		OnClick(browser.Dispatcher(EventEventClick{e})).
+		OnClick(browser.Dispatcher(EventEventClick{e, start})).
 		Pointer()
 
 }
+
+// color is the color of e.
+func color(s *State, e *cal.EventItem) string {
+	if s.Color == nil {
+		return "lightgray" // TODO: use theme?
+	}
+	return s.Color(e)
+}
+
+var itemLiftedShadow = browser.BoxShadow{
+	HOffset: browser.Size{},
+	VOffset: browser.Size{Value: 4, Unit: browser.UnitPX},
+	Blur:    browser.Size{Value: 8, Unit: browser.UnitPX},
+	Spread:  browser.Size{Value: 0, Unit: browser.UnitPX},
+	Color:   "rgba(0, 0, 0, 0.1)",
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/nlandolfi/elos/web-client/components/calendar/extra"
 	"github.com/nlandolfi/elos/web-client/components/calendar/ltr"
 	"github.com/nlandolfi/elos/web-client/components/calendar/mgrid"
 	"github.com/nlandolfi/spin/apps/cal"
@@ -15,8 +16,16 @@ type State struct {
 	Theme       *ui.Theme        `json:"-"`
 	Time        *time.Time       `json:"-"`
 	EventItems  []*cal.EventItem `json:"-"` // this is a pointer
+	Extras      extra.Table      `json:"-"`
 	SelectedKey *string          `json:"-"`
 
+	// Color is the color of an event, and Tags the tags of the events.
+	Color func(*cal.EventItem) string `json:"-"`
+	Tags  []string                    `json:"-"`
+
+	// Tag, if set, is the tag of the only events the heatmap counts.
+	Tag string
+
 	hoveredMonth time.Time
 	hoveredDay   time.Time
 }
@@ -50,6 +59,8 @@ func (s *State) Handle(e browser.Event) {
 		)
 	case EventSetTime:
 		*s.Time = e.Time
+	case EventSetTag:
+		s.Tag = e.Tag
 	case EventIncrementYear:
 		*s.Time = time.Date(
 			s.Time.Year()+1, s.Time.Month(), s.Time.Day(),
@@ -68,6 +79,7 @@ type EventDayHoverLeave struct{ time.Time }
 type EventDecrementYear struct{}
 type EventSetTime struct{ time.Time }
 type EventIncrementYear struct{}
+type EventSetTag struct{ Tag string }
 
 func (s *State) SetTheme(t *ui.Theme) {
 	s.Theme = t
@@ -76,6 +88,27 @@ func (s *State) SetTheme(t *ui.Theme) {
 func View(s *State) *browser.Node {
 	m, n := 3, 4 // 3 * 4 = 12 months
 
+	// the grids of the months show some days of those either side
+	from := time.Date(s.Time.Year(), time.January, 1, 0, 0, 0, 0, s.Time.Location()).AddDate(0, 0, -7)
+	to := time.Date(s.Time.Year()+1, time.January, 1, 0, 0, 0, 0, s.Time.Location()).AddDate(0, 0, 14)
+	es := s.EventItems
+	if s.Tag != "" {
+		es = nil
+		for _, e := range s.EventItems {
+			if s.Extras.HasTag(e, s.Tag) {
+				es = append(es, e)
+			}
+		}
+	}
+	byDay := extra.ByDay(s.Extras.Occurrences(es, from, to), from, extra.Days(from, to))
+	on := func(t time.Time) []extra.Occurrence {
+		i := extra.Days(from, t)
+		if i < 0 || i >= len(byDay) {
+			return nil
+		}
+		return byDay[i]
+	}
+
 	var rows []*browser.Node = make([]*browser.Node, m)
 
 	for i := 0; i < m; i++ {
@@ -99,7 +132,7 @@ func View(s *State) *browser.Node {
 						}).
 					OnMouseEnter(browser.Dispatcher(EventMonthHoverStart{sentinel})).
 					OnMouseLeave(browser.Dispatcher(EventMonthHoverLeave{sentinel})), // TODO: cache these again
-				monthGrid(s, sentinel),
+				monthGrid(s, sentinel, on),
 			).PaddingPX(20).FlexGrow("1")
 		}
 
@@ -126,6 +159,7 @@ func header(s *State) *browser.Node {
 				}),
 		*/
 		ui.Spacer(),
+		tags(s),
 		ltr.View(&ltr.State{
 			Theme:        s.Theme,
 			OnClickPrev:  browser.Dispatcher(EventDecrementYear{}),
@@ -135,7 +169,43 @@ func header(s *State) *browser.Node {
 	).AlignItemsCenter()
 }
 
-func monthGrid(s *State, sentinel time.Time) *browser.Node {
+// tags is the filter of the heatmap by tag.
+func tags(s *State) *browser.Node {
+	if len(s.Tags) == 0 && s.Tag == "" {
+		return ui.HStack()
+	}
+
+	bold := func(n *browser.Node) *browser.Node {
+		return n.FontWeight("700")
+	}
+	views := []*browser.Node{
+		s.Theme.Button("All tags").OnClickDispatch(EventSetTag{}).OnlyIf(s.Tag == "", bold),
+	}
+	for _, tag := range s.Tags {
+		views = append(views, s.Theme.Button(tag).OnClickDispatch(EventSetTag{tag}).OnlyIf(tag == s.Tag, bold))
+	}
+	return ui.HStack(views...).AlignItemsCenter().FlexWrap(browser.FlexWrapWrap).MarginRightPX(10)
+}
+
+// heat is the shades of the days of the heatmap, by how many occurrences
+// they have, past the first.
+var heat = []string{"#e4ecf7", "#c3d5ee", "#9bb8e0", "#7099d0"} // TODO use theme
+
+// shade is the shade of a day with n occurrences, or "" if it has none.
+func shade(n int) string {
+	switch {
+	case n == 0:
+		return ""
+	case n > len(heat):
+		return heat[len(heat)-1]
+	default:
+		return heat[n-1]
+	}
+}
+
+// monthGrid is the grid of the month of sentinel, as a heatmap of the
+// occurrences on each day, with a dot of each of their colors.
+func monthGrid(s *State, sentinel time.Time, on func(time.Time) []extra.Occurrence) *browser.Node {
 	return mgrid.Grid(&mgrid.GridSpec{
 		PadTo6Weeks: true,
 		Time:        sentinel,
@@ -160,8 +230,8 @@ func monthGrid(s *State, sentinel time.Time) *browser.Node {
 				OnlyIf(t.Month() != sentinel.Month(),
 					func(n *browser.Node) *browser.Node {
 						return n.Color("gray")
-									}).
-				OnlyIf(cal.SameDay(t, time.Now()), // TODO get time from state
+											}).
+				OnlyIf(cal.SameDay(t, time.Now().In(t.Location())), // TODO get time from state
 					func(n *browser.Node) *browser.Node {
 						return n.Color("red") // TODO use theme
 					}).
@@ -178,19 +248,44 @@ func monthGrid(s *State, sentinel time.Time) *browser.Node {
 				OnMouseEnter(browser.Dispatcher(EventDayHoverStart{t})).
 				OnMouseLeave(browser.Dispatcher(EventDayHoverLeave{t}))
 
-			var dots []*browser.Node
-			for _, e := range s.EventItems {
-				if e.ShouldDisplayOnDay(t) {
-					dots = append(dots, s.Theme.Text("*"))
-				}
-			}
-
+			os := on(t)
 			return ui.VStack(
-				number.OnlyIf(len(dots) > 0, func(n *browser.Node) *browser.Node {
-					return n.FontWeight("700") //TextDecorationUnderline()
+				number.OnlyIf(len(os) > 0 && !cal.SameDay(s.hoveredDay, t), func(n *browser.Node) *browser.Node {
+					return n.Background(shade(len(os))).BorderRadiusPX(40)
 				}),
+				dots(s, os),
 			).FlexGrow("1").
 				FlexBasis("0px")
 		},
 	}).FlexGrow("1")
 }
+
+// dots is a dot of each of the colors of os, up to three.
+func dots(s *State, os []extra.Occurrence) *browser.Node {
+	var (
+		views []*browser.Node
+		seen  = make(map[string]bool)
+	)
+	for _, o := range os {
+		c := color(s, o.EventItem)
+		if seen[c] || len(views) == 3 {
+			continue
+		}
+		seen[c] = true
+		views = append(views, ui.VStack().
+			MinWidth(browser.Size{Value: 4, Unit: browser.UnitPX}).
+			MinHeight(browser.Size{Value: 4, Unit: browser.UnitPX}).
+			Background(c).
+			BorderRadiusPX(2).
+			MarginLeftPX(1).
+			MarginRightPX(1))
+	}
+	return ui.HStack(views...).JustifyContentCenter()
+}
+
+func color(s *State, e *cal.EventItem) string {
+	if s.Color == nil {
+		return "lightgray" // TODO: use theme?
+	}
+	return s.Color(e)
+}
@@ -0,0 +1,724 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/nlandolfi/elos/web-client/components/notes/note"
+	"github.com/russross/blackfriday/v2"
+)
+
+// Import parses Markdown into a note document; see ToNote.
+func Import(md []byte) *note.Node {
+	bf := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions))
+	return ToNote(bf.Parse(md))
+}
+
+// ToNote converts a blackfriday AST into a note document. Headings become
+// headers, except a leading level 1 heading, which becomes the document's
+// title. Math in $ and $$ delimiters becomes tex. Notes have no code of
+// their own, so code blocks and rules come over as raw HTML, and inline
+// code as plain text.
+func ToNote(root *blackfriday.Node) *note.Node {
+	doc := note.Document("", "", note.DocumentArticle)
+	s := &importState{stack: []*note.Node{doc}}
+	root.Walk(s.visit)
+	doc.Walk(checks, func(*note.Node) {})
+	doc.Walk(trim, func(*note.Node) {})
+
+	if len(doc.Children) > 0 {
+		if h := doc.Children[0]; h.Type == note.NodeHeader && h.HeaderInfo.Level == 1 {
+			if title, ok := plainText(h); ok {
+				doc.DocumentInfo.Text = title
+				doc.Children = doc.Children[1:]
+			}
+		}
+	}
+	return doc
+}
+
+type importState struct {
+	stack []*note.Node
+}
+
+func (s *importState) top() *note.Node {
+	return s.stack[len(s.stack)-1]
+}
+
+func (s *importState) add(ns ...*note.Node) {
+	top := s.top()
+	top.Children = append(top.Children, ns...)
+}
+
+func (s *importState) push(n *note.Node) {
+	s.add(n)
+	s.stack = append(s.stack, n)
+}
+
+// through is for the containers notes have no node for; their children go
+// to the enclosing node.
+func (s *importState) through() {
+	s.stack = append(s.stack, s.top())
+}
+
+func (s *importState) visit(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if !entering {
+		if n.IsContainer() {
+			s.stack = s.stack[:len(s.stack)-1]
+		}
+		return blackfriday.GoToNext
+	}
+
+	switch n.Type {
+	case blackfriday.Document:
+		s.through() // the document was made in ToNote
+	case blackfriday.BlockQuote:
+		s.push(&note.Node{Type: note.NodeQuote})
+	case blackfriday.List:
+		t := note.ListUnordered
+		if n.ListData.ListFlags&blackfriday.ListTypeOrdered != 0 {
+			t = note.ListOrdered
+		}
+		s.push(&note.Node{
+			Type:     note.NodeList,
+			ListInfo: &note.ListInfo{Type: note.ListType(t)},
+		})
+	case blackfriday.Item:
+		s.push(note.ListItem())
+	case blackfriday.Paragraph:
+		// the items of tight lists hold their text directly
+		if p := n.Parent; p != nil && p.Type == blackfriday.Item && p.Parent != nil && p.Parent.ListData.Tight {
+			s.through()
+		} else {
+			s.push(&note.Node{Type: note.NodeParagraph})
+		}
+	case blackfriday.Heading:
+		s.push(note.Header(n.HeadingData.Level))
+	case blackfriday.Emph:
+		s.push(&note.Node{Type: note.NodeItalics})
+	case blackfriday.Strong:
+		s.push(&note.Node{Type: note.NodeBold})
+	case blackfriday.Del:
+		s.through()
+	case blackfriday.Link:
+		s.push(&note.Node{
+			Type: note.NodeLink,
+			LinkInfo: &note.LinkInfo{
+				Ref:  string(n.LinkData.Destination),
+				Text: string(n.LinkData.Title),
+			},
+		})
+	case blackfriday.Image:
+		var alt bytes.Buffer
+		n.Walk(func(c *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			if entering {
+				alt.Write(c.Literal)
+			}
+			return blackfriday.GoToNext
+		})
+		s.add(note.Image("", alt.String(), string(n.LinkData.Destination)))
+		return blackfriday.SkipChildren
+	case blackfriday.Text:
+		s.add(texts(string(n.Literal))...)
+	case blackfriday.Code, blackfriday.HTMLSpan:
+		s.add(note.Text(string(n.Literal)))
+	case blackfriday.Softbreak, blackfriday.Hardbreak:
+		// the next text goes on its own line regardless
+	case blackfriday.HTMLBlock:
+		s.add(rawHTML(string(n.Literal)))
+	case blackfriday.Table:
+		s.push(note.Table(tableAlign(n)))
+	case blackfriday.TableRow:
+		s.push(&note.Node{
+			Type: note.NodeTableRow,
+			TableRowInfo: &note.TableRowInfo{
+				Header: n.Parent != nil && n.Parent.Type == blackfriday.TableHead,
+			},
+		})
+	case blackfriday.TableCell:
+		s.push(&note.Node{Type: note.NodeTableCell})
+	case blackfriday.CodeBlock, blackfriday.HorizontalRule:
+		var b bytes.Buffer
+		r := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{})
+		n.Walk(func(c *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			return r.RenderNode(&b, c, entering)
+		})
+		s.add(rawHTML(b.String()))
+		return blackfriday.SkipChildren
+	default:
+		// TableHead and TableBody group rows, which say for
+		// themselves whether they're headers, and footnotes aren't
+		// turned on.
+		if n.IsContainer() {
+			s.through()
+		}
+	}
+	return blackfriday.GoToNext
+}
+
+// tableAlign is the alignment of the columns of table, as a note
+// TableInfo has it, from the cells of its first row.
+func tableAlign(table *blackfriday.Node) string {
+	var align strings.Builder
+	table.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || n.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+		for c := n.FirstChild; c != nil; c = c.Next {
+			switch c.TableCellData.Align {
+			case blackfriday.TableAlignmentCenter:
+				align.WriteByte('c')
+			case blackfriday.TableAlignmentRight:
+				align.WriteByte('r')
+			default:
+				align.WriteByte('l')
+			}
+		}
+		return blackfriday.Terminate
+	})
+	return strings.TrimRight(align.String(), "l")
+}
+
+// rawHTML makes an html node of h, less its blank lines, which Parse
+// would skip.
+func rawHTML(h string) *note.Node {
+	var ls []string
+	for _, l := range strings.Split(h, "\n") {
+		if l != "" {
+			ls = append(ls, l)
+		}
+	}
+	return &note.Node{
+		Type:     note.NodeHTML,
+		HTMLInfo: &note.HTMLInfo{Lines: ls},
+	}
+}
+
+// texts splits text into its math and lines, one Text per line as Parse
+// would have it.
+func texts(text string) []*note.Node {
+	var ns []*note.Node
+	for text != "" {
+		i := strings.Index(text, "$")
+		if i < 0 {
+			break
+		}
+		delim := "$"
+		if strings.HasPrefix(text[i:], "$$") {
+			delim = "$$"
+		}
+		j := strings.Index(text[i+len(delim):], delim)
+		if j < 0 {
+			break
+		}
+		ns = append(ns, lines(text[:i])...)
+		tex := text[i+len(delim) : i+len(delim)+j]
+		if delim == "$$" {
+			ns = append(ns, &note.Node{
+				Type:     note.NodeTex,
+				TexInfo:  &note.TexInfo{Display: true},
+				Children: lines(tex),
+			})
+		} else {
+			ns = append(ns, note.TexInline(tex))
+		}
+		text = text[i+len(delim)+j+len(delim):]
+	}
+	return append(ns, lines(text)...)
+}
+
+// lines splits text at its line breaks; the spaces between words and
+// the runs around them stay.
+func lines(text string) []*note.Node {
+	var ns []*note.Node
+	ls := strings.Split(text, "\n")
+	for i, l := range ls {
+		if i > 0 {
+			l = strings.TrimLeft(l, " \t")
+		}
+		if i < len(ls)-1 {
+			l = strings.TrimRight(l, " \t")
+		}
+		if l != "" {
+			ns = append(ns, note.Text(l))
+		}
+	}
+	return ns
+}
+
+// checks makes n a check list if it is a list of "[ ] a" and "[x] b"
+// items, which blackfriday takes for text.
+func checks(n *note.Node) {
+	if n.Type != note.NodeList || len(n.Children) == 0 {
+		return
+	}
+	for _, item := range n.Children {
+		if _, ok := checkbox(item); !ok {
+			return
+		}
+	}
+	for _, item := range n.Children {
+		checked, _ := checkbox(item)
+		t := item.Children[0].TextInfo
+		t.Text = strings.TrimLeft(t.Text[3:], " ")
+		item.ItemInfo = &note.ItemInfo{Checked: checked}
+	}
+	n.ListInfo.Type = note.ListCheck
+}
+
+// checkbox is whether item starts with a checked or unchecked box.
+func checkbox(item *note.Node) (checked, ok bool) {
+	if len(item.Children) == 0 || item.Children[0].Type != note.NodeText {
+		return false, false
+	}
+	t := item.Children[0].TextInfo.Text
+	if len(t) > 3 && t[3] != ' ' {
+		return false, false
+	}
+	switch {
+	case strings.HasPrefix(t, "[ ]"):
+		return false, true
+	case strings.HasPrefix(t, "[x]"), strings.HasPrefix(t, "[X]"):
+		return true, true
+	}
+	return false, false
+}
+
+// trim puts n's text the way Parse would have it: without spaces at the
+// ends of lines, and with bold, italic and tex runs between texts, empty
+// if need be.
+func trim(n *note.Node) {
+	var cs []*note.Node
+	for i, c := range n.Children {
+		var next *note.Node
+		if i+1 < len(n.Children) {
+			next = n.Children[i+1]
+		}
+		afterRun := len(cs) > 0 && inline(cs[len(cs)-1])
+
+		switch {
+		case c.Type == note.NodeText:
+			if !afterRun {
+				c.TextInfo.Text = strings.TrimLeft(c.TextInfo.Text, " \t")
+			}
+			if next == nil || !inline(next) {
+				c.TextInfo.Text = strings.TrimRight(c.TextInfo.Text, " \t")
+			}
+			if c.TextInfo.Text == "" && !afterRun && (next == nil || !inline(next)) {
+				continue
+			}
+			cs = append(cs, c)
+		case inline(c):
+			if len(cs) == 0 || cs[len(cs)-1].Type != note.NodeText {
+				cs = append(cs, note.Text(""))
+			}
+			cs = append(cs, c)
+			if next == nil || (next.Type != note.NodeText && !inline(next)) {
+				cs = append(cs, note.Text(""))
+			}
+		default:
+			cs = append(cs, c)
+		}
+	}
+	n.Children = cs
+}
+
+// inline is whether the DSL writes n on the same line as the text next
+// to it.
+func inline(n *note.Node) bool {
+	switch n.Type {
+	case note.NodeItalics, note.NodeBold:
+		return true
+	case note.NodeTex:
+		return !n.TexInfo.Display
+	}
+	return false
+}
+
+// plainText is the text of n, if n holds nothing but text.
+func plainText(n *note.Node) (string, bool) {
+	var parts []string
+	for _, c := range n.Children {
+		if c.Type != note.NodeText {
+			return "", false
+		}
+		parts = append(parts, c.TextInfo.Text)
+	}
+	return strings.Join(parts, " "), true
+}
+
+// Export writes n as Markdown, formatted by Format. The document's title
+// becomes a level 1 heading and sections headings below it; environments
+// such as theorems become paragraphs led by their bold name.
+func Export(n *note.Node) ([]byte, error) {
+	var s exportState
+	n.Walk(s.pre, s.post)
+	s.WriteString("\n")
+	return Format(s.Bytes(), nil)
+}
+
+type exportState struct {
+	bytes.Buffer
+	indent   string       // of the lines of the current block
+	docs     int          // depth of documents
+	depth    int          // of sections
+	lists    []*note.Node // entered, innermost last
+	items    []int        // so far, in each list entered
+	markers  []string     // of the items entered
+	tables   []*exportTable
+	item     bool // at the start of a list item
+	inline   bool // in a run of inline nodes
+	raw      int  // depth of nodes whose text isn't escaped
+	lastText bool
+}
+
+// exportTable tracks the table being written. Markdown tables must start
+// with a single header row, so a table without one gets an empty one.
+type exportTable struct {
+	*note.TableInfo
+	cols int
+	rows int
+	row  *note.Node // the row the table was made for, if it had none
+}
+
+func (t *exportTable) separator() string {
+	var b strings.Builder
+	b.WriteString("|")
+	for i := 0; i < t.cols; i++ {
+		switch t.Alignment(i) {
+		case note.AlignCenter:
+			b.WriteString(":-:|")
+		case note.AlignRight:
+			b.WriteString("--:|")
+		default:
+			b.WriteString("---|")
+		}
+	}
+	return b.String()
+}
+
+func (s *exportState) printf(format string, a ...interface{}) {
+	fmt.Fprintf(s, format, a...)
+}
+
+func (s *exportState) newline() {
+	s.printf("\n%s", s.indent)
+}
+
+// open starts a block, a blank line after whatever came before it.
+func (s *exportState) open() {
+	switch {
+	case s.item:
+		s.item = false
+	case s.Len() > 0:
+		s.printf("\n%s", strings.TrimRight(s.indent, " "))
+		s.newline()
+	}
+	s.inline = false
+	s.lastText = false
+}
+
+// span starts an inline node, and the paragraph it is in if need be.
+func (s *exportState) span() {
+	if !s.inline {
+		s.open()
+		s.inline = true
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`#`, `\#`,
+	`$`, `\$`,
+)
+
+func (s *exportState) text(t string) {
+	s.printf("%s", markdownEscaper.Replace(t))
+}
+
+func (s *exportState) heading(level int) {
+	switch {
+	case level < 1:
+		level = 1
+	case level > 6:
+		level = 6
+	}
+	s.open()
+	s.printf("%s ", strings.Repeat("#", level))
+}
+
+func (s *exportState) env(name, text string) {
+	s.open()
+	s.printf("**%s", name)
+	if text != "" {
+		s.printf(" (")
+		s.text(text)
+		s.printf(")")
+	}
+	s.printf(".** ")
+	s.inline = true
+}
+
+func (s *exportState) pre(n *note.Node) {
+	if (n.Type == note.NodeText || n.Type == note.NodeRef) && s.lastText {
+		if len(s.tables) > 0 {
+			s.printf(" ") // a row is a single line
+		} else {
+			s.newline()
+		}
+	}
+	s.lastText = false
+
+	switch n.Type {
+	case note.NodeDocument:
+		if s.docs == 0 && n.DocumentInfo.Text != "" {
+			s.heading(1)
+			s.text(n.DocumentInfo.Text)
+		}
+		s.docs++
+	case note.NodeComment:
+		s.open()
+		s.printf("<!--")
+		s.raw++
+	case note.NodeHeader:
+		s.heading(n.HeaderInfo.Level)
+		s.inline = true
+	case note.NodeText:
+		s.span()
+		if s.raw > 0 {
+			s.printf("%s", n.TextInfo.Text)
+		} else {
+			s.text(n.TextInfo.Text)
+		}
+	case note.NodeTex:
+		if n.TexInfo.Display {
+			s.open()
+			s.printf("$$")
+			s.inline = true
+			s.lastText = true // the tex starts on the next line
+		} else {
+			s.span()
+			s.printf("$")
+		}
+		s.raw++
+	case note.NodeEquation:
+		s.open()
+		s.printf("$$")
+		s.inline = true
+		s.lastText = true
+		s.raw++
+	case note.NodeList:
+		s.open()
+		s.lists = append(s.lists, n)
+		s.items = append(s.items, 0)
+	case note.NodeListItem:
+		marker := "- "
+		if i := len(s.lists) - 1; i < 0 {
+			// an item outside of any list stands alone
+			s.open()
+		} else {
+			s.items[i]++
+			if s.items[i] > 1 {
+				s.newline()
+			}
+			if s.lists[i].ListInfo.Type == note.ListOrdered {
+				marker = fmt.Sprintf("%d. ", s.items[i])
+			}
+		}
+		if n.ItemInfo != nil {
+			// as GitHub has them
+			if n.ItemInfo.Checked {
+				marker += "[x] "
+			} else {
+				marker += "[ ] "
+			}
+		}
+		s.printf("%s", marker)
+		s.markers = append(s.markers, marker)
+		s.indent += strings.Repeat(" ", len(marker))
+		s.item = true
+		s.inline = false
+	case note.NodeItalics, note.NodeTerm:
+		s.span()
+		s.printf("*")
+	case note.NodeBold:
+		s.span()
+		s.printf("**")
+	case note.NodeQuote:
+		s.open()
+		s.printf("> ")
+		s.indent += "> "
+		s.item = true
+	case note.NodeLink:
+		s.span()
+		s.printf("[")
+		if len(n.Children) == 0 {
+			s.text(note.UnderscoreIfNot(n.LinkInfo.Text))
+		}
+	case note.NodeRef:
+		s.span()
+		s.printf("[")
+		if len(n.Children) == 0 {
+			if n.RefInfo.Text != "" {
+				s.text(n.RefInfo.Text)
+			} else {
+				s.text(n.RefInfo.Ref)
+			}
+		}
+	case note.NodeParagraph:
+		s.open()
+		s.inline = true
+	case note.NodeDefinition:
+		s.env("Definition", n.DefinitionInfo.Text)
+	case note.NodeTheorem:
+		s.env("Theorem", n.TheoremInfo.Text)
+	case note.NodeCorollary:
+		s.env("Corollary", n.CorollaryInfo.Text)
+	case note.NodeExample:
+		s.env("Example", n.ExampleInfo.Text)
+	case note.NodeAlgorithm:
+		s.env("Algorithm", n.AlgorithmInfo.Text)
+	case note.NodeProposition:
+		s.env("Proposition", n.PropositionInfo.Text)
+	case note.NodeProblem:
+		s.env("Problem", n.ProblemInfo.Text)
+	case note.NodeSection:
+		// the title is the level 1 heading
+		s.heading(s.depth + 2)
+		s.text(n.SectionInfo.Text)
+		s.depth++
+	case note.NodeImage:
+		s.span()
+		s.printf("![")
+		s.text(n.ImageInfo.Text)
+		s.printf("](%s)", n.ImageInfo.Path)
+	case note.NodeTable:
+		s.open()
+		t := &exportTable{TableInfo: n.TableInfo}
+		for _, r := range n.Children {
+			if len(r.Children) > t.cols {
+				t.cols = len(r.Children)
+			}
+		}
+		s.tables = append(s.tables, t)
+	case note.NodeTableRow:
+		if len(s.tables) == 0 {
+			s.open()
+			s.tables = append(s.tables, &exportTable{
+				TableInfo: &note.TableInfo{},
+				cols:      len(n.Children),
+				row:       n,
+			})
+		}
+		t := s.tables[len(s.tables)-1]
+		if t.rows > 0 {
+			s.newline()
+		} else if !n.TableRowInfo.Header {
+			s.printf("|%s", strings.Repeat("  |", t.cols))
+			s.newline()
+			s.printf("%s", t.separator())
+			s.newline()
+		}
+		t.rows++
+		s.printf("|")
+	case note.NodeTableCell:
+		s.printf(" ")
+		s.inline = true
+	case note.NodeVStack, note.NodeHStack:
+	case note.NodeHTML:
+		s.open()
+		for i, l := range n.HTMLInfo.Lines {
+			if i > 0 {
+				s.newline()
+			}
+			s.printf("%s", l)
+		}
+	case note.NodeError:
+		s.open()
+		s.text(n.ErrorInfo.Source)
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
+
+func (s *exportState) post(n *note.Node) {
+	s.lastText = n.Type == note.NodeText || n.Type == note.NodeRef
+
+	switch n.Type {
+	case note.NodeDocument:
+		s.docs--
+	case note.NodeComment:
+		s.printf("-->")
+		s.raw--
+		s.inline = false
+	case note.NodeText, note.NodeImage, note.NodeVStack, note.NodeHStack:
+	case note.NodeTex:
+		s.raw--
+		if n.TexInfo.Display {
+			s.newline()
+			s.printf("$$")
+			s.inline = false
+		} else {
+			s.printf("$")
+		}
+	case note.NodeEquation:
+		s.raw--
+		s.newline()
+		s.printf("$$")
+		s.inline = false
+	case note.NodeList:
+		s.lists = s.lists[:len(s.lists)-1]
+		s.items = s.items[:len(s.items)-1]
+		s.inline = false
+	case note.NodeListItem:
+		marker := s.markers[len(s.markers)-1]
+		s.markers = s.markers[:len(s.markers)-1]
+		s.indent = s.indent[:len(s.indent)-len(marker)]
+		s.item = false
+		s.inline = false
+	case note.NodeItalics, note.NodeTerm:
+		s.printf("*")
+	case note.NodeBold:
+		s.printf("**")
+	case note.NodeQuote:
+		s.indent = strings.TrimSuffix(s.indent, "> ")
+		s.item = false
+		s.inline = false
+	case note.NodeLink:
+		s.printf("](%s)", n.LinkInfo.Ref)
+	case note.NodeRef:
+		s.printf("](#%s)", n.RefInfo.Ref)
+	case note.NodeTable:
+		s.tables = s.tables[:len(s.tables)-1]
+		s.inline = false
+	case note.NodeTableRow:
+		t := s.tables[len(s.tables)-1]
+		if t.rows == 1 && n.TableRowInfo.Header {
+			s.newline()
+			s.printf("%s", t.separator())
+		}
+		if t.row == n {
+			s.tables = s.tables[:len(s.tables)-1]
+			s.inline = false
+		}
+	case note.NodeTableCell:
+		s.printf(" |")
+	case note.NodeSection:
+		s.depth--
+		s.inline = false
+	case note.NodeHeader, note.NodeParagraph, note.NodeHTML, note.NodeError,
+		note.NodeDefinition, note.NodeTheorem, note.NodeCorollary, note.NodeExample,
+		note.NodeAlgorithm, note.NodeProposition, note.NodeProblem:
+		s.inline = false
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
@@ -0,0 +1,98 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nlandolfi/elos/web-client/components/notes/note"
+)
+
+var importCases = []string{
+	"# Title\n\nSome *text* with **bold** and $x^2$ math,\nand a [link](http://x.com) too.\n",
+	"## Part\n\n- one\n- two $a$\n  - nested\n\n1. first\n2. second\n",
+	"> quoted text\n> more\n",
+	"$$\n\\int f\n$$\n",
+	"```go\nfunc main() {\n}\n```\n\n| a | b |\n|---|--:|\n| 1 | *2* |\n",
+	"![alt text](img.png)\n\n---\n",
+	"- [ ] open\n- [x] done\n",
+	"2 * 3 = 6\n",
+	"`my_var` and snake_case\n",
+	"*a*b*c*\n",
+	"costs $5\n",
+	"see\n.sec a b\n",
+	"a\n}\n",
+	"a\n// not a comment\n",
+	"a\n\\$\\$\n",
+	"a path\\\\*\n",
+}
+
+// Imported notes should already be in the form Parse gives them.
+func TestImportRoundTrip(t *testing.T) {
+	for _, md := range importCases {
+		n := Import([]byte(md))
+		var b bytes.Buffer
+		note.Render(&b, n)
+		m, err := note.Parse(bytes.NewBufferString(b.String()))
+		if err != nil {
+			t.Errorf("Import(%q): Parse: %v\n%s", md, err, b.String())
+			continue
+		}
+		if !note.Equal(n, m) {
+			t.Errorf("Import(%q): Render then Parse changed the note:\n%s", md, b.String())
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	raw := `.doc _ Title article
+.sec intro Intro {
+  hello *there* $x$
+  .thm big Big {
+    statement
+  }
+  .listo {
+    .item {
+      a
+    }
+  }
+  .listc {
+    [ ] open
+    [x] done
+  }
+  .table lc {
+    | x | y |
+  }
+}
+`
+	n, err := note.Parse(bytes.NewBufferString(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := Export(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Title", "Intro", "hello **there** $x$", "**Theorem (Big).** statement", "1.", "| x | y |", "[ ] open", "[x] done"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("Export: missing %q in:\n%s", want, md)
+		}
+	}
+
+	m := Import(md)
+	if got := m.DocumentInfo.Text; got != "Title" {
+		t.Errorf("Import(Export(n)) title = %q, want %q", got, "Title")
+	}
+	var table *note.Node
+	m.Walk(func(n *note.Node) {
+		if n.Type == note.NodeTable {
+			table = n
+		}
+	}, func(*note.Node) {})
+	if open := note.OpenItems(m); len(open) != 1 {
+		t.Errorf("Import(Export(n)) has %d open items, want 1", len(open))
+	}
+	if table == nil || table.TableInfo.Align != "lc" || len(table.Children) != 2 {
+		t.Errorf("Import(Export(n)) table = %v, want an lc table of an empty header and a row", table)
+	}
+}
@@ -94,6 +94,26 @@ func UnorderedList(items []string) *Node {
 	}
 }
 
+func CheckList(items []string) *Node {
+	children := make([]*Node, len(items))
+	for i, s := range items {
+		children[i] = &Node{
+			Type:     NodeListItem,
+			ItemInfo: &ItemInfo{},
+			Children: []*Node{
+				Text(s),
+			},
+		}
+	}
+	return &Node{
+		Type: NodeList,
+		ListInfo: &ListInfo{
+			Type: ListCheck,
+		},
+		Children: children,
+	}
+}
+
 func Italics(text string) *Node {
 	return &Node{
 		Type: NodeItalics,
@@ -114,7 +134,7 @@ func Bold(text string) *Node {
 
 func Quote(text string) *Node {
 	return &Node{
-		Type: NodeBold,
+		Type: NodeQuote,
 		Children: []*Node{
 			Text(text),
 		},
@@ -165,3 +185,32 @@ func Paragraph(text string) *Node {
 		},
 	}
 }
+
+func Table(align string, rows ...*Node) *Node {
+	return &Node{
+		Type: NodeTable,
+		TableInfo: &TableInfo{
+			Align: align,
+		},
+		Children: rows,
+	}
+}
+
+func TableRow(header bool, cells []string) *Node {
+	children := make([]*Node, len(cells))
+	for i, c := range cells {
+		children[i] = &Node{
+			Type: NodeTableCell,
+			Children: []*Node{
+				Text(c),
+			},
+		}
+	}
+	return &Node{
+		Type: NodeTableRow,
+		TableRowInfo: &TableRowInfo{
+			Header: header,
+		},
+		Children: children,
+	}
+}
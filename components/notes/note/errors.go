@@ -0,0 +1,84 @@
+package note
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ErrorCode string
+
+const (
+	ErrUnknownCommand   ErrorCode = "unknown-command"
+	ErrMissingArgument  ErrorCode = "missing-argument"
+	ErrBadArgument      ErrorCode = "bad-argument"
+	ErrUnfinishedInline ErrorCode = "unfinished-inline"
+	ErrUnbalancedClose  ErrorCode = "unbalanced-close"
+	ErrUnclosedBlock    ErrorCode = "unclosed-block"
+	ErrMisplaced        ErrorCode = "misplaced"
+	ErrRead             ErrorCode = "read"
+	ErrDuplicateSymbol  ErrorCode = "duplicate-symbol"
+	ErrUndefinedSymbol  ErrorCode = "undefined-symbol"
+)
+
+// ParseError describes a single problem found while parsing the DSL.
+// Line and Column are 1-based; Column is a byte offset into the raw line.
+// Offset is the 0-based byte offset into the input. Command is the dot
+// command involved, without the dot, if there was one.
+type ParseError struct {
+	Line    int
+	Column  int
+	Offset  int
+	Command string
+	Code    ErrorCode
+	Msg     string
+}
+
+func (e *ParseError) Error() string {
+	if e.Command != "" {
+		return fmt.Sprintf("line %d:%d: .%s: %s", e.Line, e.Column, e.Command, e.Msg)
+	}
+	return fmt.Sprintf("line %d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// ErrorList is the error returned by Parse, and what Resolve reports; it
+// holds every problem found, in the order they were found.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	var b strings.Builder
+	for i, e := range l {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(e.Error())
+	}
+	return b.String()
+}
+
+// Err returns nil if the list is empty, and the list otherwise.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Lines returns the distinct line numbers that have errors, in order.
+func (l ErrorList) Lines() []int {
+	var ls []int
+	seen := make(map[int]bool, len(l))
+	for _, e := range l {
+		if seen[e.Line] {
+			continue
+		}
+		seen[e.Line] = true
+		ls = append(ls, e.Line)
+	}
+	return ls
+}
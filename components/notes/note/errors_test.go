@@ -0,0 +1,100 @@
+package note
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		src                  string
+		code                 ErrorCode
+		cmd                  string
+		line, column, offset int
+	}{
+		{".doc a b\n.nope {\n}\n", ErrUnknownCommand, "nope", 2, 2, 10},
+		{".doc a b\n  .sec x\n  }\n", ErrMissingArgument, "sec", 2, 3, 11},
+		{".doc a b\n.header two {\n}\n", ErrBadArgument, "header", 2, 1, 9},
+		{".doc a b\n.table lx {\n}\n", ErrBadArgument, "table", 2, 1, 9},
+		{".doc a b\n.item [?] {\n}\n", ErrBadArgument, "item", 2, 1, 9},
+		{".doc a b\nsome *bold\n", ErrUnfinishedInline, "", 2, 6, 14},
+		{".doc a b\n}\n", ErrUnbalancedClose, "", 2, 1, 9},
+		{".doc a b\n.sec x X {\n  .p {\n  }\n", ErrUnclosedBlock, "", 2, 1, 9},
+		{".doc a b\n.cell {\n}\n", ErrMisplaced, "cell", 2, 1, 9},
+	}
+
+	for _, c := range cases {
+		_, err := Parse(strings.NewReader(c.src))
+		l, ok := err.(ErrorList)
+		if !ok || len(l) == 0 {
+			t.Errorf("Parse(%q) = %v, want an ErrorList", c.src, err)
+			continue
+		}
+		e := l[0]
+		if e.Code != c.code || e.Command != c.cmd || e.Line != c.line || e.Column != c.column || e.Offset != c.offset {
+			t.Errorf("Parse(%q) = %s .%s at %d:%d (%d), want %s .%s at %d:%d (%d)", c.src,
+				e.Code, e.Command, e.Line, e.Column, e.Offset,
+				c.code, c.cmd, c.line, c.column, c.offset)
+		}
+	}
+}
+
+// The parser used to let "}" close a document, take the "{" of a block as
+// an argument, and exit on a bad header level.
+func TestParseQuietChanges(t *testing.T) {
+	n, err := Parse(strings.NewReader(".doc a b\n.sec s Title {\n  x\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sec := n.Children[0]; sec.SectionInfo.Text != "Title" || sec.SectionInfo.Symbol != "s" {
+		t.Errorf("got section %q %q, want s Title", sec.SectionInfo.Symbol, sec.SectionInfo.Text)
+	}
+
+	_, err = Parse(strings.NewReader(".doc a b\n.sec s {\n  x\n}\n"))
+	if l, ok := err.(ErrorList); !ok || l[0].Code != ErrMissingArgument {
+		t.Errorf("a section with a \"{\" for its title parsed: %v", err)
+	}
+
+	_, err = Parse(strings.NewReader(".doc a b\nx\n}\ny\n"))
+	if l, ok := err.(ErrorList); !ok || l[0].Code != ErrUnbalancedClose {
+		t.Errorf("\"}\" closed the document: %v", err)
+	}
+
+	_, errs := ParseTolerant(strings.NewReader(".doc a b\n.header x {\n  Title\n}\nafter\n"))
+	if len(errs) != 1 || errs[0].Code != ErrBadArgument {
+		t.Errorf("a bad header level gave %v, want one bad argument", errs)
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	if err := ErrorList(nil).Err(); err != nil {
+		t.Errorf("ErrorList(nil).Err() = %v, want nil", err)
+	}
+
+	_, err := Parse(strings.NewReader(".doc a b\n.nope\n}\n"))
+	l, ok := err.(ErrorList)
+	if !ok || len(l) != 2 {
+		t.Fatalf("Parse = %v, want two errors", err)
+	}
+	if l.Err() == nil {
+		t.Error("Err() = nil for a list of two errors")
+	}
+	if got, want := l.Error(), "line 2:2: .nope: unknown command\nline 3:1: unexpected \"}\", no open block"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got := l.Lines(); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("Lines() = %v, want [2 3]", got)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func TestParseReadError(t *testing.T) {
+	_, err := Parse(failingReader{})
+	if l, ok := err.(ErrorList); !ok || l[0].Code != ErrRead {
+		t.Errorf("Parse(failingReader) = %v, want a read error", err)
+	}
+}
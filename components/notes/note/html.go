@@ -0,0 +1,331 @@
+package note
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// KaTeX renders the math spans RenderHTML writes, once the page loads.
+const (
+	katexCSS        = "https://cdn.jsdelivr.net/npm/katex@0.15.2/dist/katex.min.css"
+	katexJS         = "https://cdn.jsdelivr.net/npm/katex@0.15.2/dist/katex.min.js"
+	katexAutoRender = "https://cdn.jsdelivr.net/npm/katex@0.15.2/dist/contrib/auto-render.min.js"
+)
+
+const htmlStyle = `
+body { max-width: 50em; margin: 2em auto; padding: 0 1em; font-family: serif; line-height: 1.5; }
+.vstack { display: flex; flex-direction: column; }
+.hstack { display: flex; flex-direction: row; gap: 1em; }
+.env { margin: 1em 0; }
+.env-title { font-weight: bold; }
+.env-title .env-name { font-weight: normal; }
+.theorem, .corollary, .proposition { font-style: italic; }
+figure { text-align: center; }
+pre.error { color: red; }
+ul.check { list-style: none; padding-left: 1em; }
+table { border-collapse: collapse; margin: 1em auto; }
+th, td { padding: 0.25em 0.75em; border-bottom: 1px solid #ddd; }
+`
+
+type htmlState struct {
+	io.Writer
+	*Numbering
+	docs     int // depth of documents
+	depth    int // of sections
+	tables   []*htmlTable
+	lastText bool
+}
+
+// htmlTable tracks where in a table the cells being written are.
+type htmlTable struct {
+	*TableInfo
+	header bool // whether the current row is
+	col    int
+}
+
+// cell is the tag and alignment of the next cell.
+func (s *htmlState) cell() (tag string, align Alignment) {
+	if len(s.tables) == 0 {
+		return "td", AlignLeft
+	}
+	t := s.tables[len(s.tables)-1]
+	tag = "td"
+	if t.header {
+		tag = "th"
+	}
+	align = t.Alignment(t.col)
+	t.col++
+	return tag, align
+}
+
+func (s *htmlState) printf(format string, a ...interface{}) {
+	fmt.Fprintf(s, format, a...)
+}
+
+func (s *htmlState) text(t string) {
+	s.printf("%s", html.EscapeString(t))
+}
+
+func (s *htmlState) attr(v string) string {
+	return html.EscapeString(v)
+}
+
+func (s *htmlState) env(n *Node, class, name, symbol, text string) {
+	s.printf("<section class=\"env %s\"", class)
+	if symbol != "" {
+		s.printf(" id=\"%s\"", s.attr(symbol))
+	}
+	s.printf(">\n<p class=\"env-title\">%s %s", name, s.Numbers[n])
+	if text != "" {
+		s.printf(" <span class=\"env-name\">(")
+		s.text(text)
+		s.printf(")</span>")
+	}
+	s.printf(".</p>\n")
+}
+
+func (s *htmlState) pre(n *Node) {
+	// text from separate lines must not run together
+	if (n.Type == NodeText || n.Type == NodeRef) && s.lastText {
+		s.printf("\n")
+	}
+	s.lastText = false
+
+	switch n.Type {
+	case NodeDocument:
+		if s.docs == 0 {
+			s.printf("<!doctype html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+			s.printf("<title>")
+			s.text(n.DocumentInfo.Text)
+			s.printf("</title>\n")
+			s.printf("<link rel=\"stylesheet\" href=\"%s\">\n", katexCSS)
+			s.printf("<script defer src=\"%s\"></script>\n", katexJS)
+			s.printf("<script defer src=\"%s\" onload=\"renderMathInElement(document.body)\"></script>\n", katexAutoRender)
+			s.printf("<style>%s</style>\n", htmlStyle)
+			s.printf("</head>\n<body>\n")
+		}
+		s.docs++
+		s.printf("<article class=\"%s\"", s.attr(string(n.DocumentInfo.Type)))
+		if n.DocumentInfo.Symbol != "" {
+			s.printf(" id=\"%s\"", s.attr(n.DocumentInfo.Symbol))
+		}
+		s.printf(">\n")
+		if n.DocumentInfo.Text != "" {
+			s.printf("<h1 class=\"title\">")
+			s.text(n.DocumentInfo.Text)
+			s.printf("</h1>\n")
+		}
+	case NodeComment:
+		s.printf("<!--")
+	case NodeHeader:
+		s.printf("<h%d>", headerLevel(n.HeaderInfo.Level))
+	case NodeText:
+		s.text(n.TextInfo.Text)
+	case NodeTex:
+		if n.TexInfo.Display {
+			s.printf("<div class=\"math display\">\\[")
+		} else {
+			s.printf("<span class=\"math inline\">\\(")
+		}
+	case NodeEquation:
+		s.printf("<div class=\"math display equation\"")
+		if n.EquationInfo.Symbol != "" {
+			s.printf(" id=\"%s\"", s.attr(n.EquationInfo.Symbol))
+		}
+		s.printf(">\\[")
+	case NodeList:
+		switch n.ListInfo.Type {
+		case ListOrdered:
+			s.printf("<ol>\n")
+		case ListCheck:
+			s.printf("<ul class=\"check\">\n")
+		default:
+			s.printf("<ul>\n")
+		}
+	case NodeListItem:
+		s.printf("<li>")
+		if n.ItemInfo != nil {
+			s.printf("<input type=\"checkbox\" disabled")
+			if n.ItemInfo.Checked {
+				s.printf(" checked")
+			}
+			s.printf("> ")
+		}
+	case NodeItalics:
+		s.printf("<em>")
+	case NodeBold:
+		s.printf("<strong>")
+	case NodeQuote:
+		s.printf("<blockquote>\n")
+	case NodeTable:
+		s.tables = append(s.tables, &htmlTable{TableInfo: n.TableInfo})
+		s.printf("<table>\n")
+	case NodeTableRow:
+		if len(s.tables) > 0 {
+			t := s.tables[len(s.tables)-1]
+			t.header, t.col = n.TableRowInfo.Header, 0
+		}
+		s.printf("<tr>")
+	case NodeTableCell:
+		tag, align := s.cell()
+		s.printf("<%s style=\"text-align: %s\">", tag, align)
+	case NodeLink:
+		s.printf("<a href=\"%s\">", s.attr(n.LinkInfo.Ref))
+		if len(n.Children) == 0 {
+			s.text(UnderscoreIfNot(n.LinkInfo.Text))
+		}
+	case NodeRef:
+		s.printf("<a class=\"ref\" href=\"#%s\">", s.attr(n.RefInfo.Ref))
+		if len(n.Children) == 0 {
+			if name, ok := s.Lookup(n.RefInfo.Ref); ok {
+				s.text(name)
+			} else {
+				s.text(n.RefInfo.Ref)
+			}
+		}
+	case NodeParagraph:
+		s.printf("<p>")
+	case NodeDefinition:
+		s.env(n, "definition", "Definition", n.DefinitionInfo.Symbol, n.DefinitionInfo.Text)
+	case NodeTheorem:
+		s.env(n, "theorem", "Theorem", n.TheoremInfo.Symbol, n.TheoremInfo.Text)
+	case NodeCorollary:
+		s.env(n, "corollary", "Corollary", n.CorollaryInfo.Symbol, n.CorollaryInfo.Text)
+	case NodeExample:
+		s.env(n, "example", "Example", n.ExampleInfo.Symbol, n.ExampleInfo.Text)
+	case NodeAlgorithm:
+		s.env(n, "algorithm", "Algorithm", n.AlgorithmInfo.Symbol, n.AlgorithmInfo.Text)
+	case NodeProposition:
+		s.env(n, "proposition", "Proposition", n.PropositionInfo.Symbol, n.PropositionInfo.Text)
+	case NodeProblem:
+		s.env(n, "problem", "Problem", n.ProblemInfo.Symbol, n.ProblemInfo.Text)
+	case NodeSection:
+		s.depth++
+		s.printf("<section")
+		if n.SectionInfo.Symbol != "" {
+			s.printf(" id=\"%s\"", s.attr(n.SectionInfo.Symbol))
+		}
+		// h1 is the document title
+		s.printf(">\n<h%d>%s ", headerLevel(s.depth+1), s.Numbers[n])
+		s.text(n.SectionInfo.Text)
+		s.printf("</h%d>\n", headerLevel(s.depth+1))
+	case NodeImage:
+		s.printf("<figure")
+		if n.ImageInfo.Symbol != "" {
+			s.printf(" id=\"%s\"", s.attr(n.ImageInfo.Symbol))
+		}
+		s.printf(">\n<img src=\"%s\" alt=\"%s\">\n", s.attr(n.ImageInfo.Path), s.attr(n.ImageInfo.Text))
+	case NodeVStack:
+		s.printf("<div class=\"vstack\">\n")
+	case NodeHStack:
+		s.printf("<div class=\"hstack\">\n")
+	case NodeTerm:
+		s.printf("<dfn>")
+	case NodeHTML:
+		for _, l := range n.HTMLInfo.Lines {
+			s.printf("%s\n", l)
+		}
+	case NodeError:
+		s.printf("<pre class=\"error\">")
+		s.text(n.ErrorInfo.Source)
+		s.printf("</pre>\n")
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
+
+func (s *htmlState) post(n *Node) {
+	s.lastText = n.Type == NodeText || n.Type == NodeRef
+	switch n.Type {
+	case NodeDocument:
+		s.printf("</article>\n")
+		s.docs--
+		if s.docs == 0 {
+			s.printf("</body>\n</html>\n")
+		}
+	case NodeComment:
+		s.printf("-->\n")
+	case NodeHeader:
+		s.printf("</h%d>\n", headerLevel(n.HeaderInfo.Level))
+	case NodeText, NodeHTML, NodeError:
+	case NodeTex:
+		if n.TexInfo.Display {
+			s.printf("\\]</div>\n")
+		} else {
+			s.printf("\\)</span>")
+		}
+	case NodeEquation:
+		s.printf(" \\tag{%s}\\]</div>\n", s.Numbers[n])
+	case NodeList:
+		if n.ListInfo.Type == ListOrdered {
+			s.printf("</ol>\n")
+		} else {
+			s.printf("</ul>\n")
+		}
+	case NodeListItem:
+		s.printf("</li>\n")
+	case NodeItalics:
+		s.printf("</em>")
+	case NodeBold:
+		s.printf("</strong>")
+	case NodeQuote:
+		s.printf("</blockquote>\n")
+	case NodeTable:
+		s.tables = s.tables[:len(s.tables)-1]
+		s.printf("</table>\n")
+	case NodeTableRow:
+		s.printf("</tr>\n")
+	case NodeTableCell:
+		if len(s.tables) > 0 && s.tables[len(s.tables)-1].header {
+			s.printf("</th>")
+		} else {
+			s.printf("</td>")
+		}
+	case NodeLink, NodeRef:
+		s.printf("</a>")
+	case NodeParagraph:
+		s.printf("</p>\n")
+	case NodeDefinition, NodeTheorem, NodeCorollary, NodeExample,
+		NodeAlgorithm, NodeProposition, NodeProblem:
+		s.printf("</section>\n")
+	case NodeSection:
+		s.depth--
+		s.printf("</section>\n")
+	case NodeImage:
+		s.printf("<figcaption>Figure %s", s.Numbers[n])
+		if n.ImageInfo.Text != "" {
+			s.printf(": ")
+			s.text(n.ImageInfo.Text)
+		}
+		s.printf("</figcaption>\n</figure>\n")
+	case NodeVStack, NodeHStack:
+		s.printf("</div>\n")
+	case NodeTerm:
+		s.printf("</dfn>")
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
+
+// headerLevel clamps l to the levels HTML has.
+func headerLevel(l int) int {
+	switch {
+	case l < 1:
+		return 1
+	case l > 6:
+		return 6
+	default:
+		return l
+	}
+}
+
+// RenderHTML writes n as a standalone HTML page. Math is left in KaTeX's
+// \( \) and \[ \] delimiters, and the page loads KaTeX to typeset it.
+// Numbers and refs are as Resolve would have them.
+func RenderHTML(w io.Writer, n *Node) {
+	var s htmlState
+	s.Writer = w
+	s.Numbering, _ = number(n)
+	n.Walk(s.pre, s.post)
+}
@@ -0,0 +1,88 @@
+package note
+
+import "testing"
+
+const htmlNote = `.doc notes Notes-<&>-more article
+.header 2 {
+  A <header>
+}
+.sec intro Introduction {
+  .def d1 Group {
+    a set & an operation, "quoted" <b>
+  }
+  .thm pyth Pythagoras {
+    by
+    .ref d1
+    and
+    .ref e1
+    and
+    .ref nowhere
+    .ref pyth {
+      this one
+    }
+  }
+  .cor c _ {
+  }
+  .prop p _ {
+  }
+  .ex x _ {
+  }
+  .prob q _ {
+  }
+  .alg a _ {
+  }
+  .eq e1 {
+    a^2 + b^2 < c^2
+  }
+  $$
+  \int_0^1 f
+  $$
+  .par {
+    *bold* _it_ $x < 1$ 2 \* 3
+  }
+  .sec more More {
+    .quote {
+      to be
+    }
+    .img fig A-figure fig.png {
+    }
+    .link https://example.com/?a=1&b=2 Example {
+      click here
+    }
+  }
+}
+.sec lists Lists {
+  .list {
+    .item {
+      one
+    }
+  }
+  .listo {
+    .item {
+      first
+    }
+  }
+  .listc {
+    [ ] open
+    [x] done
+  }
+  .table lc {
+    | a | b |
+    |---|---|
+    | 1 | 2 |
+  }
+  .hstack {
+    .vstack {
+      x
+    }
+  }
+  // a comment
+  .html {{{
+<div class="x">raw</div>
+  }}}
+}
+`
+
+func TestRenderHTML(t *testing.T) {
+	checkGolden(t, "note.golden.html", htmlNote, RenderHTML)
+}
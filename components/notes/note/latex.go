@@ -0,0 +1,428 @@
+package note
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const latexPreamble = `\usepackage[utf8]{inputenc}
+\usepackage{amsmath,amssymb,amsthm}
+\usepackage{graphicx}
+\usepackage{hyperref}
+`
+
+// beamer defines these environments itself
+const latexTheorems = `\newtheorem{theorem}{Theorem}[section]
+\newtheorem{corollary}[theorem]{Corollary}
+\theoremstyle{definition}
+\newtheorem{definition}[theorem]{Definition}
+\newtheorem{example}[theorem]{Example}
+\newtheorem{problem}[theorem]{Problem}
+`
+
+// and these it doesn't
+const latexExtraTheorems = `\theoremstyle{plain}
+\newtheorem{proposition}[theorem]{Proposition}
+\theoremstyle{definition}
+\newtheorem{algorithm}[theorem]{Algorithm}
+`
+
+type latexState struct {
+	io.Writer
+	slides   bool
+	docs     int // depth of documents
+	depth    int // of sections
+	stack    []*Node
+	inFrame  bool
+	cols     []int // cells so far in the current row of each table
+	lastText bool
+	*Numbering
+}
+
+func (s *latexState) printf(format string, a ...interface{}) {
+	fmt.Fprintf(s, format, a...)
+}
+
+func (s *latexState) text(t string) {
+	s.printf("%s", latexEscaper.Replace(t))
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+	`|`, `\textbar{}`,
+)
+
+var latexURLEscaper = strings.NewReplacer(`%`, `\%`, `#`, `\#`)
+
+// latexLabel makes a \label from a symbol, as in "thm:pythagoras".
+func latexLabel(prefix, symbol string) string {
+	return prefix + ":" + strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9',
+			r == '.', r == '-', r == ':':
+			return r
+		default:
+			return '-'
+		}
+	}, symbol)
+}
+
+// latexEnvs maps the environment node types to their LaTeX environment
+// and label prefix.
+var latexEnvs = map[NodeType][2]string{
+	NodeTheorem:     {"theorem", "thm"},
+	NodeCorollary:   {"corollary", "cor"},
+	NodeProposition: {"proposition", "prop"},
+	NodeDefinition:  {"definition", "def"},
+	NodeExample:     {"example", "ex"},
+	NodeProblem:     {"problem", "prob"},
+	NodeAlgorithm:   {"algorithm", "alg"},
+}
+
+// symbol returns the symbol and title text of the environment-like n,
+// and the prefix its labels get.
+func symbol(n *Node) (sym, text, prefix string) {
+	switch n.Type {
+	case NodeSection:
+		return n.SectionInfo.Symbol, n.SectionInfo.Text, "sec"
+	case NodeEquation:
+		return n.EquationInfo.Symbol, "", "eq"
+	case NodeImage:
+		return n.ImageInfo.Symbol, n.ImageInfo.Text, "fig"
+	case NodeTheorem:
+		return n.TheoremInfo.Symbol, n.TheoremInfo.Text, latexEnvs[n.Type][1]
+	case NodeCorollary:
+		return n.CorollaryInfo.Symbol, n.CorollaryInfo.Text, latexEnvs[n.Type][1]
+	case NodeProposition:
+		return n.PropositionInfo.Symbol, n.PropositionInfo.Text, latexEnvs[n.Type][1]
+	case NodeDefinition:
+		return n.DefinitionInfo.Symbol, n.DefinitionInfo.Text, latexEnvs[n.Type][1]
+	case NodeExample:
+		return n.ExampleInfo.Symbol, n.ExampleInfo.Text, latexEnvs[n.Type][1]
+	case NodeProblem:
+		return n.ProblemInfo.Symbol, n.ProblemInfo.Text, latexEnvs[n.Type][1]
+	case NodeAlgorithm:
+		return n.AlgorithmInfo.Symbol, n.AlgorithmInfo.Text, latexEnvs[n.Type][1]
+	}
+	return "", "", ""
+}
+
+func (s *latexState) label(n *Node) string {
+	sym, _, prefix := symbol(n)
+	return latexLabel(prefix, sym)
+}
+
+// define writes the \label of n, if it defines a symbol.
+func (s *latexState) define(n *Node) {
+	if sym, _, _ := symbol(n); sym != "" && s.Symbols[sym] == n {
+		s.printf("\\label{%s}", s.label(n))
+	}
+}
+
+func (s *latexState) parent() *Node {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return s.stack[len(s.stack)-1]
+}
+
+// columns is the number of cells in the longest row of table.
+func columns(table *Node) int {
+	var c int
+	for _, r := range table.Children {
+		if r.Type == NodeTableRow && len(r.Children) > c {
+			c = len(r.Children)
+		}
+	}
+	return c
+}
+
+// In slides, whatever isn't a section goes on a frame, titled with the
+// section it is in.
+func (s *latexState) openFrame() {
+	s.printf("\\begin{frame}")
+	if p := s.parent(); p != nil && p.Type == NodeSection {
+		s.printf("{")
+		s.text(p.SectionInfo.Text)
+		s.printf("}")
+	}
+	s.printf("\n")
+	s.inFrame = true
+}
+
+func (s *latexState) closeFrame() {
+	if s.inFrame {
+		s.printf("\n\\end{frame}\n")
+		s.inFrame = false
+	}
+}
+
+var latexSections = []string{"section", "subsection", "subsubsection", "paragraph"}
+
+func latexSection(depth int) string {
+	if depth < 0 {
+		return latexSections[0]
+	}
+	if depth >= len(latexSections) {
+		return latexSections[len(latexSections)-1]
+	}
+	return latexSections[depth]
+}
+
+func (s *latexState) pre(n *Node) {
+	if s.slides && s.docs > 0 {
+		switch p := s.parent(); {
+		case n.Type == NodeSection:
+			s.closeFrame()
+		case !s.inFrame && (p.Type == NodeSection || p.Type == NodeDocument):
+			s.openFrame()
+		}
+	}
+	defer func() { s.stack = append(s.stack, n) }()
+
+	if (n.Type == NodeText || n.Type == NodeRef) && s.lastText {
+		s.printf("\n")
+	}
+	s.lastText = false
+
+	switch n.Type {
+	case NodeDocument:
+		if s.docs == 0 {
+			s.slides = n.DocumentInfo.Type == DocumentSlides
+			if s.slides {
+				s.printf("\\documentclass{beamer}\n")
+			} else {
+				s.printf("\\documentclass{article}\n")
+			}
+			s.printf("%s", latexPreamble)
+			if !s.slides {
+				s.printf("%s", latexTheorems)
+			}
+			s.printf("%s", latexExtraTheorems)
+			s.printf("\n\\title{")
+			s.text(n.DocumentInfo.Text)
+			s.printf("}\n\\date{}\n\n\\begin{document}\n")
+			if s.slides {
+				s.printf("\\frame{\\titlepage}\n")
+			} else {
+				s.printf("\\maketitle\n")
+			}
+		}
+		s.docs++
+	case NodeComment:
+		s.printf("%%")
+	case NodeHeader:
+		if s.slides {
+			s.printf("\n{\\large\\bfseries ")
+		} else {
+			s.printf("\n\\%s*{", latexSection(n.HeaderInfo.Level-1))
+		}
+	case NodeText:
+		if p := s.parent(); p != nil && (p.Type == NodeTex || p.Type == NodeEquation || p.Type == NodeComment) {
+			s.printf("%s", n.TextInfo.Text)
+		} else {
+			s.text(n.TextInfo.Text)
+		}
+	case NodeTex:
+		if n.TexInfo.Display {
+			s.printf("\n\\[\n")
+		} else {
+			s.printf("$")
+		}
+	case NodeEquation:
+		s.printf("\n\\begin{equation}")
+		s.define(n)
+		s.printf("\n")
+	case NodeList:
+		if n.ListInfo.Type == ListOrdered {
+			s.printf("\n\\begin{enumerate}\n")
+		} else {
+			s.printf("\n\\begin{itemize}\n")
+		}
+	case NodeListItem:
+		switch {
+		case n.ItemInfo == nil:
+			s.printf("\\item ")
+		case n.ItemInfo.Checked:
+			s.printf("\\item[$\\boxtimes$] ")
+		default:
+			s.printf("\\item[$\\square$] ")
+		}
+	case NodeItalics, NodeTerm:
+		s.printf("\\emph{")
+	case NodeBold:
+		s.printf("\\textbf{")
+	case NodeQuote:
+		s.printf("\n\\begin{quote}\n")
+	case NodeLink:
+		s.printf("\\href{%s}{", latexURLEscaper.Replace(n.LinkInfo.Ref))
+		if len(n.Children) == 0 {
+			s.text(UnderscoreIfNot(n.LinkInfo.Text))
+		}
+	case NodeRef:
+		// LaTeX does its own numbering, so only the kind is written out
+		t, ok := s.Symbols[n.RefInfo.Ref]
+		switch {
+		case len(n.Children) > 0 && ok:
+			s.printf("\\hyperref[%s]{", s.label(t))
+		case len(n.Children) > 0:
+			s.printf("{")
+		case !ok:
+			s.printf("\\ref{%s}", n.RefInfo.Ref)
+		case t.Type == NodeEquation:
+			s.printf("%s~\\eqref{%s}", kinds[t.Type], s.label(t))
+		default:
+			s.printf("%s~\\ref{%s}", kinds[t.Type], s.label(t))
+		}
+	case NodeParagraph:
+		s.printf("\n")
+	case NodeTheorem, NodeCorollary, NodeProposition, NodeDefinition,
+		NodeExample, NodeProblem, NodeAlgorithm:
+		_, text, _ := symbol(n)
+		s.printf("\n\\begin{%s}", latexEnvs[n.Type][0])
+		if text != "" {
+			s.printf("[")
+			s.text(text)
+			s.printf("]")
+		}
+		s.define(n)
+		s.printf("\n")
+	case NodeSection:
+		s.printf("\n\\%s{", latexSection(s.depth))
+		s.text(n.SectionInfo.Text)
+		s.printf("}")
+		s.define(n)
+		s.printf("\n")
+		s.depth++
+	case NodeImage:
+		s.printf("\n\\begin{figure}[h]\n\\centering\n")
+		s.printf("\\includegraphics[width=0.8\\linewidth]{%s}\n", n.ImageInfo.Path)
+	case NodeTable:
+		c := columns(n)
+		if c == 0 {
+			c = 1 // a tabular needs a column, even if it is empty
+		}
+		var spec strings.Builder
+		for i := 0; i < c; i++ {
+			spec.WriteString(string(n.TableInfo.Alignment(i))[:1])
+		}
+		s.printf("\n\\begin{center}\n\\begin{tabular}{%s}\n", spec.String())
+		s.cols = append(s.cols, 0)
+	case NodeTableRow:
+		if len(s.cols) > 0 {
+			s.cols[len(s.cols)-1] = 0
+		}
+	case NodeTableCell:
+		if i := len(s.cols) - 1; i >= 0 {
+			if s.cols[i] > 0 {
+				s.printf(" & ")
+			}
+			s.cols[i]++
+		}
+	case NodeVStack, NodeHStack:
+	case NodeHTML:
+		// there's no LaTeX for raw HTML; keep it, commented out
+		for _, l := range n.HTMLInfo.Lines {
+			s.printf("%% %s\n", l)
+		}
+	case NodeError:
+		s.printf("%% error: %s\n", n.ErrorInfo.Source)
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
+
+func (s *latexState) post(n *Node) {
+	s.stack = s.stack[:len(s.stack)-1]
+	s.lastText = n.Type == NodeText || n.Type == NodeRef
+
+	switch n.Type {
+	case NodeDocument:
+		s.docs--
+		if s.docs == 0 {
+			s.closeFrame()
+			s.printf("\n\\end{document}\n")
+		}
+	case NodeComment:
+		s.printf("\n")
+	case NodeHeader:
+		s.printf("}\n")
+		if s.slides {
+			s.printf("\n")
+		}
+	case NodeText, NodeHTML, NodeError, NodeVStack, NodeHStack, NodeTableCell:
+	case NodeTable:
+		s.cols = s.cols[:len(s.cols)-1]
+		s.printf("\\end{tabular}\n\\end{center}\n")
+	case NodeTableRow:
+		s.printf(" \\\\\n")
+		if n.TableRowInfo.Header {
+			s.printf("\\hline\n")
+		}
+	case NodeRef:
+		if len(n.Children) > 0 {
+			s.printf("}")
+		}
+	case NodeTex:
+		if n.TexInfo.Display {
+			s.printf("\n\\]\n")
+		} else {
+			s.printf("$")
+		}
+	case NodeEquation:
+		s.printf("\n\\end{equation}\n")
+	case NodeList:
+		if n.ListInfo.Type == ListOrdered {
+			s.printf("\n\\end{enumerate}\n")
+		} else {
+			s.printf("\n\\end{itemize}\n")
+		}
+	case NodeListItem:
+		s.printf("\n")
+	case NodeItalics, NodeTerm, NodeBold, NodeLink:
+		s.printf("}")
+	case NodeQuote:
+		s.printf("\n\\end{quote}\n")
+	case NodeParagraph:
+		s.printf("\n")
+	case NodeTheorem, NodeCorollary, NodeProposition, NodeDefinition,
+		NodeExample, NodeProblem, NodeAlgorithm:
+		s.printf("\n\\end{%s}\n", latexEnvs[n.Type][0])
+	case NodeSection:
+		s.depth--
+		if s.slides {
+			s.closeFrame()
+		}
+	case NodeImage:
+		if n.ImageInfo.Text != "" {
+			s.printf("\\caption{")
+			s.text(n.ImageInfo.Text)
+			s.printf("}")
+		}
+		s.define(n)
+		s.printf("\n\\end{figure}\n")
+	default:
+		panic(fmt.Sprintf("unkown type: %q", n.Type))
+	}
+}
+
+// RenderLaTeX writes n as a LaTeX article, or as a beamer deck if it is
+// a DocumentSlides document. Environments with symbols get labels, such
+// as "thm:pythagoras", and refs to those symbols become \ref's, as in
+// "Theorem~\ref{thm:pythagoras}".
+func RenderLaTeX(w io.Writer, n *Node) {
+	var s latexState
+	s.Writer = w
+	s.Numbering, _ = number(n)
+	n.Walk(s.pre, s.post)
+}
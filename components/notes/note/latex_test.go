@@ -0,0 +1,106 @@
+package note
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden compares what render writes of the note src with the golden
+// file testdata/name.
+func checkGolden(t *testing.T, name, src string, render func(io.Writer, *Node)) {
+	n, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	var b bytes.Buffer
+	render(&b, n)
+
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile error: %v", err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v, perhaps go test -update will fix", err)
+	}
+
+	if got, want := b.Bytes(), expected; !bytes.Equal(got, want) {
+		gotGolden := filepath.Join(t.TempDir(), "got."+name)
+		if err := ioutil.WriteFile(gotGolden, got, 0644); err != nil {
+			t.Fatalf("ioutil.WriteFile error: %v", err)
+		}
+		t.Fatalf("golden mismatch: see %s and %s", gotGolden, golden)
+	}
+}
+
+const latexArticle = `.doc notes Notes-&-more article
+.sec intro Introduction {
+  .def d/1 Group {
+    a set & an operation, 100% of the time
+  }
+  .thm pyth Pythagoras {
+    by
+    .ref d/1
+    and
+    .ref e1
+    and
+    .ref nowhere
+    .ref pyth {
+      this one
+    }
+  }
+  .eq e1 {
+    a^2 + b^2 = c^2
+  }
+  \$5 for a \_, #1 {x} ~ ^ | \ *bold* _it_ $x_1$
+  .table {
+  }
+  .table lc {
+    | a | b |
+    |---|---|
+    | 1 | 2 |
+  }
+  .listc {
+    [ ] open
+    [x] done
+  }
+}
+`
+
+const latexSlides = `.doc deck A-Deck slides
+.sec first First {
+  hello
+  .listo {
+    .item {
+      one
+    }
+  }
+}
+.sec second Second {
+  .thm t _ {
+    see
+    .ref first
+  }
+}
+`
+
+func TestRenderLaTeX(t *testing.T) {
+	for name, src := range map[string]string{
+		"article.golden.tex": latexArticle,
+		"slides.golden.tex":  latexSlides,
+	} {
+		t.Run(name, func(t *testing.T) {
+			checkGolden(t, name, src, RenderLaTeX)
+		})
+	}
+}
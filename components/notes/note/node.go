@@ -1,6 +1,9 @@
 package note
 
-import "fmt"
+import (
+	"fmt"
+	"reflect"
+)
 
 type NodeType string
 
@@ -17,7 +20,8 @@ const (
 	NodeBold        NodeType = "bold"
 	NodeQuote       NodeType = "quote"
 	NodeTable       NodeType = "table"
-	NodeTableRow    NodeType = "table"
+	NodeTableRow    NodeType = "table-row"
+	NodeTableCell   NodeType = "table-cell"
 	NodeLink        NodeType = "link"
 	NodeRef         NodeType = "ref"
 	NodeParagraph   NodeType = "paragraph"
@@ -34,6 +38,7 @@ const (
 	NodeProposition NodeType = "proposition"
 	NodeProblem     NodeType = "problem"
 	NodeHTML        NodeType = "html"
+	NodeError       NodeType = "error"
 )
 
 type Node struct {
@@ -43,6 +48,9 @@ type Node struct {
 	*HeaderInfo
 	*TextInfo
 	*ListInfo
+	*ItemInfo
+	*TableInfo
+	*TableRowInfo
 	*EquationInfo
 	*TexInfo
 	*LinkInfo
@@ -58,8 +66,25 @@ type Node struct {
 	*ImageInfo
 	*AlgorithmInfo
 	*HTMLInfo
+	*ErrorInfo
 
 	Children []*Node
+
+	// Start and End are where Parse found the node in its input, End being
+	// exclusive. Both are zero for nodes that weren't parsed.
+	Start, End Position
+}
+
+// Position is a place in the raw DSL text. Offset is a 0-based byte
+// offset; Line and Column are 1-based, Column counting bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 func (n *Node) Debug() string {
@@ -75,7 +100,16 @@ func (n *Node) Debug() string {
 	case NodeText:
 		return fmt.Sprintf("%s:%s", string(n.Type), string(n.TextInfo.Text))
 	case NodeListItem:
+		if n.ItemInfo != nil {
+			return fmt.Sprintf("%s:checked=%t", string(n.Type), n.ItemInfo.Checked)
+		}
 		return fmt.Sprintf("%s", string(n.Type))
+	case NodeTable:
+		return fmt.Sprintf("%s:%s", string(n.Type), n.TableInfo.Align)
+	case NodeTableRow:
+		return fmt.Sprintf("%s:header=%t", string(n.Type), n.TableRowInfo.Header)
+	case NodeError:
+		return fmt.Sprintf("%s:%s,%q", string(n.Type), n.ErrorInfo.Code, n.ErrorInfo.Source)
 	default:
 		return "no debug info available"
 	}
@@ -132,6 +166,15 @@ type HTMLInfo struct {
 	Lines []string
 }
 
+// ErrorInfo records a line ParseTolerant could not make sense of. Source
+// is the offending line, less any opening brace; Block is whether it opened
+// a block, in which case the node holds whatever was inside it.
+type ErrorInfo struct {
+	Code   ErrorCode
+	Source string
+	Block  bool
+}
+
 type TextInfo struct {
 	Text string
 }
@@ -140,6 +183,39 @@ type ListInfo struct {
 	Type ListType
 }
 
+// TableInfo holds the alignment of each column of a table, one letter per
+// column: 'l', 'c' or 'r'. Columns past the end are aligned left.
+type TableInfo struct {
+	Align string
+}
+
+type Alignment string
+
+const (
+	AlignLeft   Alignment = "left"
+	AlignCenter Alignment = "center"
+	AlignRight  Alignment = "right"
+)
+
+// Alignment is the alignment of column i, counting from 0.
+func (t *TableInfo) Alignment(i int) Alignment {
+	if i >= len(t.Align) {
+		return AlignLeft
+	}
+	switch t.Align[i] {
+	case 'c':
+		return AlignCenter
+	case 'r':
+		return AlignRight
+	default:
+		return AlignLeft
+	}
+}
+
+type TableRowInfo struct {
+	Header bool
+}
+
 type ImageInfo struct {
 	Symbol string
 	Text   string
@@ -151,16 +227,24 @@ type ListType string
 const (
 	ListOrdered   = "ordered"
 	ListUnordered = "unordered"
-	ListCheck
+	ListCheck     = "check"
 )
 
+// ItemInfo is held by the list items that have a checkbox, which are
+// those of check lists, mostly.
+type ItemInfo struct {
+	Checked bool
+}
+
 type LinkInfo struct {
 	Ref  string
 	Text string
 }
 
 type RefInfo struct {
-	Ref  string
+	Ref string
+	// Text is the name of what Ref refers to, as Resolve finds it. It is
+	// not written, so Equal ignores it.
 	Text string
 }
 
@@ -196,6 +280,73 @@ func walk(n *Node, pre, post func(n *Node)) {
 	post(n)
 }
 
+// Equal reports whether a and b are the same tree: the same types, the
+// same info and equal children, in order. Source positions, and the text
+// Resolve gives refs, are ignored.
+func Equal(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(a.Children) != len(b.Children) {
+		return false
+	}
+	x, y := *a, *b
+	x.Children, y.Children = nil, nil
+	x.Start, x.End, y.Start, y.End = Position{}, Position{}, Position{}, Position{}
+	if x.RefInfo != nil && y.RefInfo != nil {
+		xr, yr := *x.RefInfo, *y.RefInfo
+		xr.Text, yr.Text = "", ""
+		x.RefInfo, y.RefInfo = &xr, &yr
+	}
+	if !reflect.DeepEqual(x, y) {
+		return false
+	}
+	for i := range a.Children {
+		if !Equal(a.Children[i], b.Children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// NodeAt returns the innermost node whose source contains the byte offset,
+// or nil if there isn't one.
+func (root *Node) NodeAt(offset int) *Node {
+	if offset < root.Start.Offset || offset >= root.End.Offset {
+		return nil
+	}
+	for _, c := range root.Children {
+		if n := c.NodeAt(offset); n != nil {
+			return n
+		}
+	}
+	return root
+}
+
+// NodeAtLine is like NodeAt, but for a 1-based line and column.
+func (root *Node) NodeAtLine(line, column int) *Node {
+	var found *Node
+	root.Walk(func(n *Node) {
+		if contains(n, line, column) {
+			found = n
+		}
+	}, func(n *Node) {})
+	return found
+}
+
+func contains(n *Node, line, column int) bool {
+	if line < n.Start.Line || line > n.End.Line {
+		return false
+	}
+	if line == n.Start.Line && column < n.Start.Column {
+		return false
+	}
+	if line == n.End.Line && column >= n.End.Column {
+		return false
+	}
+	return true
+}
+
 func AssetPaths(n *Node) []string {
 	var as []string
 	n.Walk(func(n *Node) {
@@ -221,3 +372,14 @@ func Items(n *Node) int {
 
 	return i
 }
+
+// OpenItems returns the items of n with unchecked checkboxes, in order.
+func OpenItems(n *Node) []*Node {
+	var is []*Node
+	n.Walk(func(c *Node) {
+		if c.Type == NodeListItem && c.ItemInfo != nil && !c.ItemInfo.Checked {
+			is = append(is, c)
+		}
+	}, func(c *Node) {})
+	return is
+}
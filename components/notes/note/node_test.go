@@ -0,0 +1,151 @@
+package note
+
+import (
+	"strings"
+	"testing"
+)
+
+const positionSrc = `.doc a b
+.sec s S {
+  x *bold* $t$
+  .thm t _ {
+    y
+  }
+}
+`
+
+// describe is n's type, and its text if it is text.
+func describe(n *Node) string {
+	if n == nil {
+		return "nil"
+	}
+	if n.Type == NodeText {
+		return "text:" + n.TextInfo.Text
+	}
+	return string(n.Type)
+}
+
+func TestNodeAt(t *testing.T) {
+	n, err := Parse(strings.NewReader(positionSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		offset int
+		want   string
+	}{
+		{0, describe(n)},
+		{9, string(NodeSection)},
+		{22, "text:x "},
+		{24, string(NodeBold)},
+		{26, "text:bold"},
+		{29, string(NodeBold)},
+		{31, string(NodeTex)},
+		{32, "text:t"},
+		{37, string(NodeTheorem)},
+		{52, "text:y"},
+		{len(positionSrc), "nil"},
+		{-1, "nil"},
+	} {
+		if got := describe(n.NodeAt(c.offset)); got != c.want {
+			t.Errorf("NodeAt(%d) = %s, want %s", c.offset, got, c.want)
+		}
+	}
+}
+
+func TestNodeAtNoDoc(t *testing.T) {
+	// the document made for a note without a ".doc" line spans it all
+	const src = "hello\n.p {\n  x\n}\n"
+	n, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		offset int
+		want   string
+	}{
+		{0, "text:hello"},
+		{5, string(NodeDocument)},
+		{6, string(NodeParagraph)},
+		{13, "text:x"},
+		{15, string(NodeParagraph)},
+		{len(src), "nil"},
+	} {
+		if got := describe(n.NodeAt(c.offset)); got != c.want {
+			t.Errorf("NodeAt(%d) = %s, want %s", c.offset, got, c.want)
+		}
+	}
+	if got := describe(n.NodeAtLine(3, 3)); got != "text:x" {
+		t.Errorf("NodeAtLine(3, 3) = %s, want text:x", got)
+	}
+}
+
+func TestNodeAtLine(t *testing.T) {
+	n, err := Parse(strings.NewReader(positionSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		line, column int
+		want         string
+	}{
+		{2, 1, string(NodeSection)},
+		{3, 3, "text:x "},
+		{3, 5, string(NodeBold)},
+		{3, 6, "text:bold"},
+		{3, 13, "text:t"},
+		{4, 3, string(NodeTheorem)},
+		{5, 5, "text:y"},
+		{6, 3, string(NodeTheorem)},
+		{7, 1, string(NodeSection)},
+		{9, 1, "nil"},
+	} {
+		if got := describe(n.NodeAtLine(c.line, c.column)); got != c.want {
+			t.Errorf("NodeAtLine(%d, %d) = %s, want %s", c.line, c.column, got, c.want)
+		}
+	}
+
+	// the offsets and the lines agree
+	n.Walk(func(m *Node) {
+		if m.Type != NodeText || m.Start == m.End {
+			return
+		}
+		if got := n.NodeAtLine(m.Start.Line, m.Start.Column); got != n.NodeAt(m.Start.Offset) {
+			t.Errorf("at %s (%d): NodeAtLine is %s, NodeAt is %s",
+				m.Start, m.Start.Offset, describe(got), describe(n.NodeAt(m.Start.Offset)))
+		}
+	}, func(*Node) {})
+}
+
+func TestPositions(t *testing.T) {
+	n, err := Parse(strings.NewReader(positionSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sec := n.Children[0]
+	bold := sec.Children[1]
+	thm := sec.Children[len(sec.Children)-1]
+	for _, c := range []struct {
+		name      string
+		got, want Position
+	}{
+		{"section start", sec.Start, Position{Offset: 9, Line: 2, Column: 1}},
+		{"section end", sec.End, Position{Offset: 59, Line: 7, Column: 2}},
+		{"bold start", bold.Start, Position{Offset: 24, Line: 3, Column: 5}},
+		{"bold end", bold.End, Position{Offset: 30, Line: 3, Column: 11}},
+		{"bold text start", bold.Children[0].Start, Position{Offset: 25, Line: 3, Column: 6}},
+		{"theorem start", thm.Start, Position{Offset: 37, Line: 4, Column: 3}},
+		{"theorem end", thm.End, Position{Offset: 57, Line: 6, Column: 4}},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %s (%d), want %s (%d)", c.name, c.got, c.got.Offset, c.want, c.want.Offset)
+		}
+	}
+	if got := (Position{Line: 3, Column: 5}).String(); got != "3:5" {
+		t.Errorf("String() = %q, want %q", got, "3:5")
+	}
+}
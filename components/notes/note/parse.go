@@ -31,26 +31,109 @@ func MustParse(r io.Reader) *Node {
 	return n
 }
 
+// Parse reads the DSL from r. If there are any problems, it keeps going
+// and returns all of them as an ErrorList.
 func Parse(r io.Reader) (*Node, error) {
-	var p parseState
-	p.Scanner = bufio.NewScanner(r)
-	p.stack = []*Node{{}}
-	if err := p.run(); err != nil {
-		return nil, fmt.Errorf("md.Parse: %v", err)
+	p := newParseState(r, false)
+	p.run()
+	if err := p.errs.Err(); err != nil {
+		return nil, err
 	}
-	if len(p.stack[0].Children) > 0 {
-		return p.stack[0].Children[0], nil
-	} else {
-		return Document("", "", DocumentArticle), nil
+	return p.root(), nil
+}
+
+// ParseTolerant is like Parse, but always returns a usable tree alongside
+// the problems it found. Blocks left open are closed at the end of the
+// input, unknown commands become NodeError nodes and unfinished inline
+// runs are kept as text. It is meant for previewing text mid-edit.
+func ParseTolerant(r io.Reader) (*Node, ErrorList) {
+	p := newParseState(r, true)
+	p.run()
+	return p.root(), p.errs
+}
+
+func newParseState(r io.Reader, tolerant bool) *parseState {
+	s := &parseState{
+		Scanner:  bufio.NewScanner(r),
+		stack:    []*Node{{}},
+		tolerant: tolerant,
+	}
+	// track byte offsets, which the scanner's lines don't carry
+	s.Scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			s.lineStart = s.offset
+			s.offset += advance
+		}
+		return advance, token, err
+	})
+	return s
+}
+
+// root is the parsed document. Anything not under a ".doc" line is put in
+// an untitled article, so that the result is always a single document; it
+// spans the whole input, as a ".doc" on the first line would.
+func (s *parseState) root() *Node {
+	cs := s.stack[0].Children
+	if len(cs) == 1 && cs[0].Type == NodeDocument {
+		return cs[0]
+	}
+	n := Document("", "", DocumentArticle, cs...)
+	if s.lineNumber > 0 {
+		n.Start, n.End = Position{Offset: 0, Line: 1, Column: 1}, s.end
 	}
+	return n
 }
 
 type parseState struct {
 	*bufio.Scanner
 	stack      []*Node
 	lineNumber int
+	offset     int // byte offset of the next line
+	lineStart  int // byte offset of the current line
+	lineLen    int // bytes in the current line
+	indent     int // bytes of leading whitespace on the current line
+	width      int // bytes in the current line, less surrounding whitespace
 	inTex      bool
 	inHTML     bool
+	tolerant   bool
+
+	errs ErrorList
+	end  Position // of the last line, once run
+}
+
+// pos is the position of the i'th byte after the current line's indent.
+func (s *parseState) pos(i int) Position {
+	return Position{
+		Offset: s.lineStart + s.indent + i,
+		Line:   s.lineNumber,
+		Column: s.indent + i + 1,
+	}
+}
+
+// lineSpan sets n to span the current line, less surrounding whitespace.
+func (s *parseState) lineSpan(n *Node) *Node {
+	n.Start, n.End = s.pos(0), s.pos(s.width)
+	return n
+}
+
+func (s *parseState) errorf(col int, cmd string, code ErrorCode, format string, a ...interface{}) {
+	s.errorAt(Position{
+		Offset: s.lineStart + col - 1,
+		Line:   s.lineNumber,
+		Column: col,
+	}, cmd, code, format, a...)
+}
+
+func (s *parseState) errorAt(p Position, cmd string, code ErrorCode, format string, a ...interface{}) {
+	s.errs = append(s.errs, &ParseError{
+		Line:    p.Line,
+		Column:  p.Column,
+		Offset:  p.Offset,
+		Command: cmd,
+		Code:    code,
+		Msg:     fmt.Sprintf(format, a...),
+	})
 }
 
 func (s *parseState) addchild(n *Node) {
@@ -58,7 +141,9 @@ func (s *parseState) addchild(n *Node) {
 	last.Children = append(last.Children, n)
 }
 
+// push opens a block; blocks always begin at the start of a line.
 func (s *parseState) push(n *Node) {
+	n.Start = s.pos(0)
 	if len(s.stack) > 0 {
 		s.addchild(n)
 	}
@@ -67,6 +152,17 @@ func (s *parseState) push(n *Node) {
 }
 
 func (s *parseState) pop() {
+	// the root of the stack is a sentinel, and documents are never
+	// closed, so neither may be popped by a "}".
+	if len(s.stack) <= 1 || s.stack[len(s.stack)-1].Type == NodeDocument {
+		s.errorf(s.indent+1, "", ErrUnbalancedClose, "unexpected \"}\", no open block")
+		return
+	}
+	n := s.stack[len(s.stack)-1]
+	n.End = s.pos(s.width)
+	if n.Type == NodeTex {
+		s.inTex = false
+	}
 	s.stack = s.stack[:len(s.stack)-1]
 }
 
@@ -77,6 +173,33 @@ func (s *parseState) current() *NodeType {
 	return &s.stack[len(s.stack)-1].Type
 }
 
+// arguments checks that the command has at least n arguments, recording an
+// error if not. It always returns args padded to n+1 so the command can
+// still open its block, which keeps the following "}"s balanced.
+func (s *parseState) arguments(args []string, n int) []string {
+	if len(args) >= n+1 {
+		return args
+	}
+	s.errorf(s.indent+1, args[0], ErrMissingArgument, "want %d arguments, got %d", n, len(args)-1)
+	for len(args) < n+1 {
+		args = append(args, "")
+	}
+	return args
+}
+
+// arg undoes UnderscoreIfNot.
+func arg(s string) string {
+	if s == "_" {
+		return ""
+	}
+	return s
+}
+
+// argText undoes the quoting Render applies to text arguments.
+func argText(s string) string {
+	return strings.ReplaceAll(arg(s), "-", " ")
+}
+
 type lineMode string
 
 const (
@@ -88,6 +211,8 @@ const (
 type lineState struct {
 	children []*Node
 	mode     lineMode
+	start    int // byte offset at which mode began
+	open     int // byte offset of its delimiters, as "$$" for tex
 	b        strings.Builder
 }
 
@@ -136,20 +261,47 @@ func (s *lineState) reset() {
 	s.b.Reset()
 }
 
-func (s *parseState) consumeText(text string) error {
+// inline sets the span of n, an inline node made from text[from:to]; the
+// children of a delimited run span its inside.
+func (s *parseState) inline(n *Node, from, to int) *Node {
+	n.Start, n.End = s.pos(from), s.pos(to)
+	for _, c := range n.Children {
+		c.Start, c.End = s.pos(from+1), s.pos(to-1)
+	}
+	return n
+}
+
+// escapable are the characters which a "\" before makes plain text, as
+// "\*" is a "*" and not the start of bold. Tex is read as written.
+const escapable = `\*_$.}/|[`
+
+func (s *parseState) consumeText(text string) {
 	var ls lineState
 
+	var escaped bool
+	skipped := -1 // the offset of the last "$" skipped as half a "$$"
 	for i, r := range text {
+		if escaped {
+			ls.b.WriteRune(r)
+			escaped = false
+			continue
+		}
+		if r == '\\' && ls.mode != lineTex && i+1 < len(text) && strings.IndexByte(escapable, text[i+1]) >= 0 {
+			escaped = true
+			continue
+		}
 		switch m := modeFor(r); m {
 		case lineTex, lineBold, lineItalics:
 			if m == lineTex && i+1 < len(text) && modeFor(rune(text[i+1])) == lineTex {
 				// skip this guy as it is an opening or closing $ for display mode.
+				skipped = i
 				continue
 			}
 			// if we are alread in this mode, end it.
 			if ls.mode == m {
-				ls.children = append(ls.children, ls.node())
+				ls.children = append(ls.children, s.inline(ls.node(), ls.start, i+1))
 				ls.reset()
+				ls.start = i + 1
 				continue
 			}
 
@@ -158,78 +310,125 @@ func (s *parseState) consumeText(text string) error {
 				ls.b.WriteRune(r)
 				continue
 			}
-			ls.children = append(ls.children, ls.node())
+			open := i
+			if i > 0 && skipped == i-1 {
+				open = skipped
+			}
+			if ls.mode == "" {
+				ls.children = append(ls.children, s.lineText(ls.node(), ls.start, open))
+			} else {
+				// a bold or italic run, closed by the start of another;
+				// the empty text keeps the runs and text alternating, as
+				// they do when a run is closed by its own delimiter.
+				n := ls.node()
+				n.Start, n.End = s.pos(ls.start), s.pos(i)
+				n.Children[0].Start, n.Children[0].End = s.pos(ls.start+1), s.pos(i)
+				ls.children = append(ls.children, n, s.lineText(Text(""), i, i))
+			}
 			ls.reset()
 			ls.mode = m
+			ls.start, ls.open = i, open
 		default:
 			ls.b.WriteRune(r)
 		}
 	}
 
 	if ls.mode != "" {
-		return fmt.Errorf("line %q \n unfinished mode %q", text, ls.mode)
+		s.errorf(s.indent+ls.start+1, "", ErrUnfinishedInline, "unfinished %s", ls.mode)
+		if !s.tolerant {
+			return
+		}
+		// keep the unfinished run, delimiters and all, as plain text
+		ls.children = append(ls.children, s.lineText(Text(text[ls.open:]), ls.open, len(text)))
+		ls.reset()
+		ls.start = len(text)
 	}
 
-	ls.children = append(ls.children, ls.node())
+	ls.children = append(ls.children, s.lineText(ls.node(), ls.start, len(text)))
 
 	for _, c := range ls.children {
 		s.addchild(c)
 	}
+}
 
-	return nil
+// lineText sets the span of n, plain text made from text[from:to].
+func (s *parseState) lineText(n *Node, from, to int) *Node {
+	n.Start, n.End = s.pos(from), s.pos(to)
+	return n
 }
 
-func (s *parseState) consumeLine(line string) error {
+func (s *parseState) consumeLine(line string) {
 	s.lineNumber += 1
+	s.lineLen = len(line)
 	if line == "" {
-		return nil
+		return
 	}
+	s.indent = len(line) - len(strings.TrimLeft(line, " \t"))
+	s.width = len(strings.TrimSpace(line))
 	if s.inHTML {
 		if strings.TrimSpace(line) == "}}}" {
 			s.inHTML = false
 			s.pop()
-			return nil
+			return
 		}
 		n := s.stack[len(s.stack)-1].HTMLInfo
 		n.Lines = append(n.Lines, line)
-		return nil
+		return
 	}
 	line = strings.TrimSpace(line)
 	if len(line) == 0 {
-		return nil
+		return
 	}
 	if line == "}" {
 		s.pop()
-		return nil
+		return
 	}
 	if line == "$$" {
 		if s.inTex {
 			s.pop()
-			return nil
+			return
 		}
+		s.inTex = true
 		s.push(&Node{
 			Type: NodeTex,
 			TexInfo: &TexInfo{
 				Display: true,
 			},
 		})
-		return nil
+		return
 	}
 
 	if len(line) >= 2 && line[:2] == "//" {
-		s.addchild(&Node{
+		s.addchild(s.lineSpan(&Node{
 			Type:     NodeComment,
-			Children: []*Node{Text(line[2:])},
-		})
-		return nil
+			Children: []*Node{s.lineText(Text(line[2:]), 2, len(line))},
+		}))
+		return
+	}
+
+	if top := s.stack[len(s.stack)-1]; top.Type == NodeList && top.ListInfo.Type == ListCheck && isCheck(line) {
+		s.consumeCheck(line)
+		return
+	}
+
+	if line[0] == '|' && s.current() != nil && *s.current() == NodeTable {
+		s.consumeRow(line)
+		return
 	}
 
 	if line[0] == '.' {
 		args := strings.Fields(line[1:])
 		if len(args) == 0 {
 			// return fmt.Errorf("line %d, just a line with %q?", s.lineNumber, line)
-			s.addchild(Text(line))
-			return nil
+			s.addchild(s.lineSpan(Text(line)))
+			return
+		}
+		// the opening brace(s) of a block are not arguments; ".doc" is the
+		// one command without a block
+		var block bool
+		if last := args[len(args)-1]; len(args) > 1 && args[0] != "doc" && strings.Trim(last, "{") == "" {
+			args = args[:len(args)-1]
+			block = true
 		}
 		switch cmd := args[0]; cmd {
 		case "html":
@@ -239,21 +438,23 @@ func (s *parseState) consumeLine(line string) error {
 				HTMLInfo: &HTMLInfo{},
 			})
 		case "doc":
+			args = s.arguments(args, 2)
 			if len(args) == 3 {
 				args = append(args, "article")
 			}
 			s.push(&Node{
 				Type: NodeDocument,
 				DocumentInfo: &DocumentInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
-					Type:   DocumentType(args[3]),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
+					Type:   DocumentType(arg(args[3])),
 				},
 			})
 		case "header":
+			args = s.arguments(args, 1)
 			i, err := strconv.ParseInt(args[1], 10, 64)
-			if err != nil {
-				log.Fatal(err)
+			if err != nil && args[1] != "" {
+				s.errorf(s.indent+1, cmd, ErrBadArgument, "level %q is not a number", args[1])
 			}
 
 			s.push(&Node{
@@ -263,22 +464,28 @@ func (s *parseState) consumeLine(line string) error {
 				},
 			})
 		case "sec":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeSection,
 				SectionInfo: &SectionInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
+		case "quote":
+			s.push(&Node{
+				Type: NodeQuote,
+			})
 		case "p", "par":
 			s.push(&Node{
 				Type: NodeParagraph,
 			})
 		case "eq":
+			args = s.arguments(args, 1)
 			s.push(&Node{
 				Type: NodeEquation,
 				EquationInfo: &EquationInfo{
-					Symbol: args[1],
+					Symbol: arg(args[1]),
 				},
 			})
 		case "tex":
@@ -302,52 +509,72 @@ func (s *parseState) consumeLine(line string) error {
 					Type: ListOrdered,
 				},
 			})
-		case "item":
+		case "listc":
 			s.push(&Node{
-				Type: NodeListItem,
+				Type: NodeList,
+				ListInfo: &ListInfo{
+					Type: ListCheck,
+				},
 			})
+		case "item":
+			n := &Node{
+				Type: NodeListItem,
+			}
+			// the box may be written "[ ]" or "[]"
+			switch box := strings.Join(args[1:], ""); box {
+			case "":
+			case "[]":
+				n.ItemInfo = &ItemInfo{}
+			case "[x]", "[X]":
+				n.ItemInfo = &ItemInfo{Checked: true}
+			default:
+				s.errorf(s.indent+1, cmd, ErrBadArgument, "checkbox %q is not [ ] or [x]", box)
+			}
+			s.push(n)
 		case "ex":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeExample,
 				ExampleInfo: &ExampleInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "def":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeDefinition,
 				DefinitionInfo: &DefinitionInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "cor":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeCorollary,
 				CorollaryInfo: &CorollaryInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "thm":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeTheorem,
 				TheoremInfo: &TheoremInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "img", "image":
-			if len(args) != 4 {
-				return fmt.Errorf("error parsing img")
-			}
+			args = s.arguments(args, 3)
 			s.push(&Node{
 				Type: NodeImage,
 				ImageInfo: &ImageInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
-					Path:   args[3],
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
+					Path:   arg(args[3]),
 				},
 			})
 		case "vstack":
@@ -360,69 +587,203 @@ func (s *parseState) consumeLine(line string) error {
 			})
 
 		case "link":
-			if len(args) != 3 {
-				return fmt.Errorf("error parsing link")
-			}
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeLink,
 				LinkInfo: &LinkInfo{
-					Ref: args[1],
+					Ref: arg(args[1]),
 					// this text appears ot be a label?
-					Text: strings.ReplaceAll(args[2], "-", " "),
+					Text: argText(args[2]),
 				},
 			})
+		case "ref":
+			// a ref reads as what it refers to, unless given a block of
+			// its own text
+			args = s.arguments(args, 1)
+			n := &Node{
+				Type: NodeRef,
+				RefInfo: &RefInfo{
+					Ref: arg(args[1]),
+				},
+			}
+			if block {
+				s.push(n)
+			} else {
+				s.addchild(s.lineSpan(n))
+			}
+		case "table":
+			var align string
+			if len(args) > 1 {
+				align = arg(args[1])
+			}
+			if strings.Trim(align, "lcr") != "" {
+				s.errorf(s.indent+1, cmd, ErrBadArgument, "alignment %q is not made of l, c and r", align)
+			}
+			s.push(&Node{
+				Type: NodeTable,
+				TableInfo: &TableInfo{
+					Align: align,
+				},
+			})
+		case "row", "hrow":
+			if c := s.current(); c == nil || *c != NodeTable {
+				s.errorf(s.indent+1, cmd, ErrMisplaced, "a row must be in a table")
+			}
+			s.push(&Node{
+				Type: NodeTableRow,
+				TableRowInfo: &TableRowInfo{
+					Header: cmd == "hrow",
+				},
+			})
+		case "cell":
+			if c := s.current(); c == nil || *c != NodeTableRow {
+				s.errorf(s.indent+1, cmd, ErrMisplaced, "a cell must be in a row")
+			}
+			s.push(&Node{
+				Type: NodeTableCell,
+			})
 		case "alg":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeAlgorithm,
 				AlgorithmInfo: &AlgorithmInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "prop":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeProposition,
 				PropositionInfo: &PropositionInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		case "prob":
+			args = s.arguments(args, 2)
 			s.push(&Node{
 				Type: NodeProblem,
 				ProblemInfo: &ProblemInfo{
-					Symbol: args[1],
-					Text:   strings.ReplaceAll(args[2], "-", " "),
+					Symbol: arg(args[1]),
+					Text:   argText(args[2]),
 				},
 			})
 		default:
-			return fmt.Errorf("line %d: unknown command: %q", s.lineNumber, cmd)
+			s.errorf(s.indent+2, cmd, ErrUnknownCommand, "unknown command")
+			if s.tolerant {
+				n := &Node{
+					Type: NodeError,
+					ErrorInfo: &ErrorInfo{
+						Code:   ErrUnknownCommand,
+						Source: "." + strings.Join(args, " "),
+						Block:  block,
+					},
+				}
+				if block {
+					s.push(n)
+				} else {
+					s.addchild(s.lineSpan(n))
+				}
+			}
 		}
 
-		return nil
+		return
 	}
 
 	if s.current() != nil && (*s.current() == NodeTex || *s.current() == NodeEquation) {
-		s.addchild(Text(line))
-		return nil
+		s.addchild(s.lineSpan(Text(line)))
+		return
+	}
+
+	s.consumeText(line)
+}
+
+// isSeparator is whether line is the "|---|---|" that follows the header
+// rows of a table.
+func isSeparator(line string) bool {
+	return strings.Trim(line, "|-: \t") == "" && strings.Contains(line, "-")
+}
+
+// consumeRow reads a table row written as "| a | b |", or the separator
+// that makes the row above it a header.
+func (s *parseState) consumeRow(line string) {
+	table := s.stack[len(s.stack)-1]
+	if isSeparator(line) {
+		if len(table.Children) == 0 || table.Children[len(table.Children)-1].Type != NodeTableRow {
+			s.errorf(s.indent+1, "", ErrMisplaced, "separator with no row above it")
+			return
+		}
+		table.Children[len(table.Children)-1].TableRowInfo.Header = true
+		return
 	}
 
-	if err := s.consumeText(line); err != nil {
-		return fmt.Errorf("line %d: %s", s.lineNumber, err)
+	row := s.lineSpan(&Node{
+		Type:         NodeTableRow,
+		TableRowInfo: &TableRowInfo{},
+	})
+	s.push(row)
+	// each cell is read as a line of its own, indented to where it starts
+	indent, from := s.indent, 1
+	for _, c := range strings.Split(strings.TrimSuffix(line[1:], "|"), "|") {
+		text := strings.TrimSpace(c)
+		s.indent = indent + from + len(c) - len(strings.TrimLeft(c, " \t"))
+		cell := &Node{Type: NodeTableCell}
+		s.push(cell)
+		cell.End = s.pos(len(text))
+		s.consumeText(text)
+		s.stack = s.stack[:len(s.stack)-1]
+		from += len(c) + 1
 	}
+	s.indent = indent
+	s.stack = s.stack[:len(s.stack)-1]
+}
 
-	return nil
+// isCheck is whether line is a check list item written as "[ ] text" or
+// "[x] text".
+func isCheck(line string) bool {
+	if len(line) < 3 || line[0] != '[' || line[2] != ']' || !strings.ContainsRune(" xX", rune(line[1])) {
+		return false
+	}
+	return len(line) == 3 || line[3] == ' ' || line[3] == '\t'
 }
 
-func (s *parseState) run() error {
+// consumeCheck reads a check list item written as "[x] text".
+func (s *parseState) consumeCheck(line string) {
+	item := s.lineSpan(&Node{
+		Type:     NodeListItem,
+		ItemInfo: &ItemInfo{Checked: line[1] != ' '},
+	})
+	s.push(item)
+	indent := s.indent
+	text := strings.TrimLeft(line[3:], " \t")
+	s.indent += len(line) - len(text)
+	s.consumeText(text)
+	s.indent = indent
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+func (s *parseState) run() {
 	for s.Scan() {
-		line := s.Text()
-		if err := s.consumeLine(line); err != nil {
-			return err
-		}
+		s.consumeLine(s.Text())
+	}
+	s.end = Position{
+		Offset: s.lineStart + s.lineLen,
+		Line:   s.lineNumber,
+		Column: s.lineLen + 1,
 	}
 	if err := s.Err(); err != nil {
-		return err
+		s.errorf(1, "", ErrRead, "%v", err)
+		return
+	}
+
+	// whatever is left open at the end of the input was never closed, so
+	// it runs to the end of the last line
+	for i := len(s.stack) - 1; i > 0; i-- {
+		n := s.stack[i]
+		n.End = s.end
+		if n.Type != NodeDocument {
+			s.errorAt(n.Start, "", ErrUnclosedBlock, "%s block is never closed", n.Type)
+		}
 	}
-	return nil
 }
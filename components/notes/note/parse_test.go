@@ -0,0 +1,87 @@
+package note
+
+import (
+	"strings"
+	"testing"
+)
+
+// inlines describes the children of n, as "text" for text and as
+// "type(text)" for runs. Parse ends each line with a text, if empty.
+func inlines(n *Node) string {
+	var ds []string
+	for _, c := range n.Children {
+		if c.Type == NodeText {
+			ds = append(ds, c.TextInfo.Text)
+			continue
+		}
+		ds = append(ds, string(c.Type)+"("+c.Children[0].TextInfo.Text+")")
+	}
+	return strings.Join(ds, "|")
+}
+
+func TestParseTolerantInline(t *testing.T) {
+	for line, want := range map[string]string{
+		"x *bold":        "x |*bold|",
+		"x _it":          "x |_it|",
+		"cost $$5":       "cost |$$5|",
+		"cost $5":        "cost |$5|",
+		"a $x$ and _b c": "a |tex(x)| and |_b c|",
+		"*a* $b":         "|bold(a)| |$b|",
+		`\*a *b`:         "*a |*b|",
+		"$x *y* _z":      "|$x *y* _z|",
+	} {
+		n, errs := ParseTolerant(strings.NewReader(".doc a b\n.p {\n  " + line + "\n}\n"))
+		if len(errs) != 1 || errs[0].Code != ErrUnfinishedInline {
+			t.Errorf("ParseTolerant(%q) errors = %v, want one unfinished inline", line, errs)
+		}
+		if got := inlines(n.Children[0]); got != want {
+			t.Errorf("ParseTolerant(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestParseTolerantCommands(t *testing.T) {
+	n, errs := ParseTolerant(strings.NewReader(".doc a b\n.nope x {\n  y\n}\n.nah\nz\n"))
+	if len(errs) != 2 || errs[0].Code != ErrUnknownCommand || errs[1].Code != ErrUnknownCommand {
+		t.Fatalf("errors = %v, want two unknown commands", errs)
+	}
+	if len(n.Children) != 3 {
+		t.Fatalf("got %d children, want 3", len(n.Children))
+	}
+
+	block := n.Children[0]
+	if block.Type != NodeError || !block.ErrorInfo.Block || block.ErrorInfo.Source != ".nope x" {
+		t.Errorf("got %s, want a block error of .nope x", block.Debug())
+	}
+	if len(block.Children) != 1 || block.Children[0].TextInfo.Text != "y" {
+		t.Errorf("the block error holds %v, want the text y", block.Children)
+	}
+	if line := n.Children[1]; line.Type != NodeError || line.ErrorInfo.Block || line.ErrorInfo.Source != ".nah" {
+		t.Errorf("got %s, want a line error of .nah", line.Debug())
+	}
+	if z := n.Children[2]; z.Type != NodeText || z.TextInfo.Text != "z" {
+		t.Errorf("got %s after the errors, want the text z", z.Debug())
+	}
+}
+
+// A note mid-edit still gives a tree, with what was typed so far.
+func TestParseTolerantMidEdit(t *testing.T) {
+	src := ".doc a b\n.sec s S {\n  .thm t _ {\n    x *y\n"
+	n, errs := ParseTolerant(strings.NewReader(src))
+
+	var codes []string
+	for _, e := range errs {
+		codes = append(codes, string(e.Code))
+	}
+	if got, want := strings.Join(codes, ","), "unfinished-inline,unclosed-block,unclosed-block"; got != want {
+		t.Errorf("errors = %s, want %s", got, want)
+	}
+
+	thm := n.Children[0].Children[0]
+	if thm.Type != NodeTheorem || inlines(thm) != "x |*y|" {
+		t.Fatalf("got %s holding %q, want a theorem holding %q", thm.Type, inlines(thm), "x |*y|")
+	}
+	if thm.End.Line != 4 || thm.End.Offset != len(src)-1 {
+		t.Errorf("theorem ends at %s, want the end of line 4", thm.End)
+	}
+}
@@ -10,6 +10,17 @@ type renderState struct {
 	io.Writer
 	indentCount int
 	inlining    bool
+	// lastText is whether the last node finished was text. Parse never
+	// makes sibling text nodes from a single line, so a text node that
+	// follows another must begin a new line.
+	lastText bool
+	// piping is whether the row being written is in the "| a | b |"
+	// shorthand.
+	piping bool
+	lists  []ListType // entered, innermost last
+	// raw is how many of the nodes entered keep their text as written:
+	// tex, equations and comments.
+	raw int
 }
 
 func (s *renderState) indent() {
@@ -49,16 +60,21 @@ func (s *renderState) nl() {
 }
 
 func (s *renderState) pre(n *Node) {
+	defer func() { s.lastText = false }()
+	if keepsText(n) {
+		s.raw++
+	}
 	switch n.Type {
 	case NodeHTML:
 		if s.inlining {
-			panic("shouldn't be inlining html")
+			s.nl()
 		}
+		s.inlining = false
 		s.indent()
 		s.printf(".html")
 		s.openBlock(3)
 		for _, l := range n.HTMLInfo.Lines {
-			s.printf(l)
+			s.printf("%s", l)
 			s.nl()
 		}
 	case NodeDocument:
@@ -102,13 +118,21 @@ func (s *renderState) pre(n *Node) {
 		s.indent()
 		s.printf(".par")
 		s.openBlock(1)
+	case NodeQuote:
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		s.printf(".quote")
+		s.openBlock(1)
 	case NodeEquation:
 		if s.inlining {
 			s.nl()
 		}
 		s.inlining = false
 		s.indent()
-		s.printf(".eq %s", n.EquationInfo.Symbol)
+		s.printf(".eq %s", UnderscoreIfNot(n.EquationInfo.Symbol))
 		s.openBlock(1)
 	case NodeList:
 		if s.inlining {
@@ -117,9 +141,12 @@ func (s *renderState) pre(n *Node) {
 		s.inlining = false
 		s.nl()
 		s.indent()
+		s.lists = append(s.lists, n.ListInfo.Type)
 		switch n.ListInfo.Type {
 		case ListOrdered:
 			s.printf(".listo")
+		case ListCheck:
+			s.printf(".listc")
 		default:
 			s.printf(".list")
 		}
@@ -130,7 +157,15 @@ func (s *renderState) pre(n *Node) {
 		}
 		s.inlining = false
 		s.indent()
+		if s.checking(n) {
+			s.printf("%s ", checkbox(n))
+			s.inlining = true
+			break
+		}
 		s.printf(".item")
+		if n.ItemInfo != nil {
+			s.printf(" %s", checkbox(n))
+		}
 		s.openBlock(1)
 	case NodeExample:
 		if s.inlining {
@@ -185,7 +220,7 @@ func (s *renderState) pre(n *Node) {
 		s.printf(".img %s %s %s",
 			UnderscoreIfNot(n.ImageInfo.Symbol),
 			UnderscoreIfNot(strings.ReplaceAll(n.ImageInfo.Text, " ", "-")),
-			n.ImageInfo.Path,
+			UnderscoreIfNot(n.ImageInfo.Path),
 		)
 		s.openBlock(1)
 	case NodeVStack:
@@ -215,6 +250,58 @@ func (s *renderState) pre(n *Node) {
 			UnderscoreIfNot(strings.ReplaceAll(n.LinkInfo.Text, " ", "-")),
 		)
 		s.openBlock(1)
+	case NodeTable:
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		s.printf(".table")
+		if n.TableInfo.Align != "" {
+			s.printf(" %s", n.TableInfo.Align)
+		}
+		s.openBlock(1)
+	case NodeTableRow:
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		if s.piping = pipeable(n); s.piping {
+			s.printf("|")
+			break
+		}
+		if n.TableRowInfo.Header {
+			s.printf(".hrow")
+		} else {
+			s.printf(".row")
+		}
+		s.openBlock(1)
+	case NodeTableCell:
+		if s.piping {
+			s.printf(" ")
+			s.inlining = true
+			break
+		}
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		s.printf(".cell")
+		s.openBlock(1)
+	case NodeRef:
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		s.printf(".ref %s", UnderscoreIfNot(n.RefInfo.Ref))
+		if len(n.Children) > 0 {
+			s.openBlock(1)
+		} else {
+			s.nl()
+		}
 	case NodeTex:
 		if n.TexInfo.Display {
 			if s.inlining {
@@ -235,15 +322,24 @@ func (s *renderState) pre(n *Node) {
 	case NodeComment:
 		if s.inlining {
 			s.nl()
-			s.inlining = false
 		}
+		s.indent()
+		s.inlining = true
 		s.printf("//")
 	case NodeText:
+		if s.inlining && s.lastText {
+			s.nl()
+			s.inlining = false
+		}
 		if !s.inlining {
 			s.indent()
 		}
+		if s.raw > 0 {
+			s.printf("%s", n.TextInfo.Text)
+		} else {
+			s.printf("%s", escape(n.TextInfo.Text, !s.inlining))
+		}
 		s.inlining = true
-		s.printf(n.TextInfo.Text)
 	case NodeBold:
 		if !s.inlining {
 			s.indent()
@@ -289,11 +385,116 @@ func (s *renderState) pre(n *Node) {
 			UnderscoreIfNot(strings.ReplaceAll(n.ProblemInfo.Text, " ", "-")),
 		)
 		s.openBlock(1)
+	case NodeError:
+		if s.inlining {
+			s.nl()
+		}
+		s.inlining = false
+		s.indent()
+		s.printf("%s", n.ErrorInfo.Source)
+		if n.ErrorInfo.Block {
+			s.openBlock(1)
+		} else {
+			s.nl()
+		}
 	default:
 		panic(fmt.Sprintf("unkown type: %q", n.Type))
 	}
 }
 
+// pipeable is whether row can be written as "| a | b |" and read back the
+// same: each of its cells must be a line, without any "|" in it, and the
+// row must not pass for a separator.
+func pipeable(row *Node) bool {
+	if len(row.Children) == 0 {
+		return false
+	}
+	separator, dash := true, false
+	for _, c := range row.Children {
+		if c.Type != NodeTableCell || !oneLine(c.Children, "|") {
+			return false
+		}
+		first := c.Children[0].TextInfo.Text
+		if len(c.Children) > 1 || strings.Trim(first, "-: \t") != "" {
+			separator = false
+		}
+		dash = dash || strings.Contains(first, "-")
+	}
+	return !separator || !dash
+}
+
+// oneLine is whether cs are a line of text, as consumeText makes them:
+// texts and runs alternating, starting and ending with text, with nothing
+// to trim at either end and none of the characters in not.
+func oneLine(cs []*Node, not string) bool {
+	if len(cs)%2 == 0 {
+		return false
+	}
+	for i, c := range cs {
+		t := c
+		if i%2 == 1 {
+			switch {
+			case c.Type == NodeBold, c.Type == NodeItalics,
+				c.Type == NodeTex && !c.TexInfo.Display:
+			default:
+				return false
+			}
+			if len(c.Children) != 1 {
+				return false
+			}
+			t = c.Children[0]
+		}
+		if t.Type != NodeText || strings.ContainsAny(t.TextInfo.Text, not+"\n") {
+			return false
+		}
+	}
+	first := cs[0].TextInfo.Text
+	last := cs[len(cs)-1].TextInfo.Text
+	return strings.TrimLeft(first, " \t") == first && strings.TrimRight(last, " \t") == last
+}
+
+// keepsText is whether the text in n is read as written, so is written
+// without escapes.
+func keepsText(n *Node) bool {
+	return n.Type == NodeTex || n.Type == NodeEquation || n.Type == NodeComment
+}
+
+// escape is text written so that it reads back as plain text: a "\"
+// before each "*", "_" and "$", before a "\" that would escape what
+// follows it, and, if text begins a line, before what would make the line
+// a command, the end of a block, a comment, a row or a check.
+func escape(text string, lineStart bool) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '*' || c == '_' || c == '$':
+			b.WriteByte('\\')
+		case c == '\\' && (i+1 == len(text) || strings.IndexByte(escapable, text[i+1]) >= 0):
+			b.WriteByte('\\')
+		case i == 0 && lineStart && (c == '.' || c == '}' || c == '|' ||
+			strings.HasPrefix(text, "//") || isCheck(text)):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// checkbox is how the box of item is written.
+func checkbox(item *Node) string {
+	if item.ItemInfo.Checked {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// checking is whether item is written as "[x] text", which only check
+// lists have.
+func (s *renderState) checking(item *Node) bool {
+	return item.ItemInfo != nil && len(s.lists) > 0 && s.lists[len(s.lists)-1] == ListCheck && oneLine(item.Children, "")
+}
+
 func UnderscoreIfNot(s string) string {
 	if s == "" {
 		return "_"
@@ -302,15 +503,18 @@ func UnderscoreIfNot(s string) string {
 }
 
 func (s *renderState) post(n *Node) {
+	s.lastText = n.Type == NodeText
+	if keepsText(n) {
+		s.raw--
+	}
 	switch n.Type {
 	case NodeHTML:
 		s.closeBlock(3)
 	case NodeDocument:
 		s.nl()
 	case NodeSection, NodeDefinition, NodeExample, NodeTheorem,
-		NodeList, NodeCorollary, NodeParagraph, NodeEquation,
-		NodeListItem, NodeImage, NodeVStack, NodeHStack, NodeLink,
-		NodeHeader, NodeAlgorithm, NodeProposition, NodeProblem:
+		NodeCorollary, NodeParagraph, NodeEquation, NodeImage, NodeVStack, NodeHStack, NodeLink,
+		NodeHeader, NodeAlgorithm, NodeProposition, NodeProblem, NodeQuote:
 		s.closeBlock(1)
 	case NodeTex:
 		if n.TexInfo.Display {
@@ -318,7 +522,49 @@ func (s *renderState) post(n *Node) {
 		} else {
 			s.printf("$")
 		}
-	case NodeText, NodeComment:
+	case NodeList:
+		s.lists = s.lists[:len(s.lists)-1]
+		s.closeBlock(1)
+	case NodeListItem:
+		if s.checking(n) {
+			s.nl()
+			s.inlining = false
+		} else {
+			s.closeBlock(1)
+		}
+	case NodeTable:
+		s.closeBlock(1)
+	case NodeTableRow:
+		if !s.piping {
+			s.closeBlock(1)
+			break
+		}
+		s.nl()
+		s.inlining = false
+		s.piping = false
+		if n.TableRowInfo.Header {
+			s.indent()
+			s.printf("|%s", strings.Repeat("---|", len(n.Children)))
+			s.nl()
+		}
+	case NodeTableCell:
+		if s.piping {
+			s.printf(" |")
+		} else {
+			s.closeBlock(1)
+		}
+	case NodeRef:
+		if len(n.Children) > 0 {
+			s.closeBlock(1)
+		}
+	case NodeError:
+		if n.ErrorInfo.Block {
+			s.closeBlock(1)
+		}
+	case NodeComment:
+		s.nl()
+		s.inlining = false
+	case NodeText:
 	case NodeBold:
 		s.printf("*")
 	case NodeItalics:
@@ -328,6 +574,9 @@ func (s *renderState) post(n *Node) {
 	}
 }
 
+// Render writes n in the DSL. For any tree Parse returns, parsing what
+// Render writes gives back an Equal tree, which is what lets a note be
+// reformatted in place.
 func Render(w io.Writer, n *Node) {
 	var s renderState
 	s.Writer = w
@@ -0,0 +1,279 @@
+package note
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+var roundTripCases = []string{
+	"",
+	".doc notes Lecture-One article\n",
+	`.doc notes Lecture-One article
+
+.sec intro Introduction {
+  .par {
+    Some *bold* and _italic_ text with $x^2$ inline.
+    A second line, which must stay a second line.
+  }
+  // a comment
+  .thm t1 Pythagoras {
+    $a^2 + b^2 = c^2$
+  }
+  .def d1 _ {
+    .listo {
+      .item {
+        one
+      }
+      .item {
+        two
+      }
+    }
+  }
+  .eq e1 {
+    x = 1
+    y = 2
+  }
+  $$
+  \int_0^1 f
+  $$
+  .img fig A-figure fig.png {
+  }
+  .link https://example.com Example {
+    click here
+  }
+  .quote {
+    to be or not to be
+  }
+  .html {{{
+<div class="x">100%</div>
+  }}}
+}
+`,
+	".doc a b\n.header 2 {\n  Title\n}\n.hstack {\n  .vstack {\n    x\n  }\n}\n",
+	".doc a b\n.cor c _ {\n}\n.ex e _ {\n}\n.alg a _ {\n}\n.prop p _ {\n}\n.prob p _ {\n}\n",
+	".doc a b\n.thm t _ {\n  by\n  .ref e\n  and\n  .ref t {\n    this\n  }\n}\n",
+	`.doc a b
+.table lcr {
+  | name | *value* | $x$ |
+  |---|---|---|
+  | one | | two _2_ |
+  .row {
+    .cell {
+      first line
+      second line
+    }
+    .cell {
+      | not a row
+    }
+  }
+  .hrow {
+    .cell {
+      ---
+    }
+  }
+  |
+}
+`,
+	".doc a b\n.row {\n  .cell {\n    x\n  }\n}\n",
+	".doc a b\n.table {\n  .cell {\n    x\n  }\n}\n",
+	`.doc a b
+.listc {
+  [ ] buy *milk*
+  [x] write $x$
+  [ ]
+  .item [x] {
+    two
+    lines
+  }
+  .item {
+    no box
+  }
+}
+.list {
+  .item [ ] {
+    boxed
+  }
+}
+`,
+	".doc a b\n\\.sec a b\n\\}\n\\// x\n\\$\\$\n2 \\* 3 and my\\_var cost \\$5, C:\\path\\\\\n*a\\*b* $a\\_b$\n",
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range roundTripCases {
+		checkRoundTrip(t, src)
+	}
+}
+
+// TestRoundTripMutations checks the round trip of notes made from the
+// cases by dropping, repeating and moving their lines, and by putting in
+// the characters the DSL gives meaning to.
+func TestRoundTripMutations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const special = "*_$\\.{}|[]/x "
+	for i := 0; i < 5000; i++ {
+		lines := strings.SplitAfter(roundTripCases[r.Intn(len(roundTripCases))], "\n")
+		for k := 1 + r.Intn(3); k > 0; k-- {
+			j := r.Intn(len(lines))
+			switch r.Intn(4) {
+			case 0:
+				lines = append(lines[:j], lines[j+1:]...)
+			case 1:
+				lines = append(lines[:j+1], lines[j:]...)
+			case 2:
+				other := strings.SplitAfter(roundTripCases[r.Intn(len(roundTripCases))], "\n")
+				lines = append(lines[:j], append([]string{other[r.Intn(len(other))]}, lines[j:]...)...)
+			case 3:
+				l := lines[j]
+				at := r.Intn(len(l) + 1)
+				lines[j] = l[:at] + string(special[r.Intn(len(special))]) + l[at:]
+			}
+			if len(lines) == 0 {
+				break
+			}
+		}
+		checkRoundTrip(t, strings.Join(lines, ""))
+	}
+}
+
+func checkRoundTrip(t *testing.T, src string) {
+	n, err := Parse(strings.NewReader(src))
+	if err != nil {
+		return // only well formed notes need survive formatting
+	}
+
+	var b bytes.Buffer
+	Render(&b, n)
+
+	m, err := Parse(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse(Render(Parse(%q))) error: %v\nrendered:\n%s", src, err, b.String())
+	}
+	if !Equal(n, m) {
+		t.Fatalf("Parse(Render(Parse(%q))) not equal\nrendered:\n%s", src, b.String())
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for text, want := range map[string]string{
+		"2 * 3 = 6":   "2 \\* 3 = 6",
+		"snake_case":  "snake\\_case",
+		"costs $5":    "costs \\$5",
+		".sec a b":    "\\.sec a b",
+		"}":           "\\}",
+		"// x":        "\\// x",
+		"/usr/bin":    "/usr/bin",
+		"[ ] x":       "\\[ ] x",
+		"| a |":       "\\| a |",
+		`C:\path`:     `C:\path`,
+		`a\*`:         `a\\\*`,
+		`a\`:          `a\\`,
+		"a. b} c// d": "a. b} c// d",
+	} {
+		var b bytes.Buffer
+		Render(&b, Paragraph(text))
+		src := ".par {\n  " + want + "\n}\n"
+		if got := b.String(); got != src {
+			t.Errorf("Render(%q) = %q, want %q", text, got, src)
+		}
+
+		n, err := Parse(strings.NewReader(src))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", want, err)
+			continue
+		}
+		if !Equal(n.Children[0], Paragraph(text)) {
+			t.Errorf("Parse(%q) = %s, want %q", want, n.Children[0].Debug(), text)
+		}
+	}
+
+	n, err := Parse(strings.NewReader(".p {\n$a\\_b$\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tex := n.Children[0].Children[1]; tex.Type != NodeTex || tex.Children[0].TextInfo.Text != `a\_b` {
+		t.Errorf("tex is not read as written: %s", n.Children[0].Debug())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := Document("a", "b", DocumentArticle, Section("s", "S", Paragraph("x")))
+	b := Document("a", "b", DocumentArticle, Section("s", "S", Paragraph("x")))
+	if !Equal(a, b) {
+		t.Fatal("Equal(a, b) = false, want true")
+	}
+	b.Children[0].Start = Position{Offset: 10, Line: 2, Column: 1}
+	if !Equal(a, b) {
+		t.Fatal("Equal should ignore positions")
+	}
+	b.Children[0].SectionInfo.Text = "T"
+	if Equal(a, b) {
+		t.Fatal("Equal(a, b) = true after changing section text, want false")
+	}
+	if Equal(Quote("x"), Bold("x")) {
+		t.Fatal("Equal(Quote, Bold) = true, want false")
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	n, err := Parse(strings.NewReader(".table lc {\n  | a | *b* |\n  |---|:-:|\n  .row {\n    .cell {\n      c\n    }\n  }\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := n.Children[0]
+	if table.Type != NodeTable || table.TableInfo.Alignment(1) != AlignCenter || table.TableInfo.Alignment(2) != AlignLeft {
+		t.Fatalf("got %s, want a table aligned lc", table.Debug())
+	}
+	if len(table.Children) != 2 {
+		t.Fatalf("got %d rows, want 2", len(table.Children))
+	}
+	header := table.Children[0]
+	if !header.TableRowInfo.Header || len(header.Children) != 2 {
+		t.Fatalf("got %s with %d cells, want a header of 2", header.Debug(), len(header.Children))
+	}
+	if b := header.Children[1]; b.Start.Column != 9 || b.Children[1].Type != NodeBold {
+		t.Errorf("second cell starts at %s holding %v, want column 9 and bold", b.Start, b.Children)
+	}
+	if table.Children[1].TableRowInfo.Header {
+		t.Error("long form .row is a header")
+	}
+
+	if _, err := Parse(strings.NewReader(".table {\n  |---|\n}\n")); err == nil {
+		t.Error("separator with no row above it parsed")
+	}
+	if _, err := Parse(strings.NewReader(".table lx {\n}\n")); err == nil {
+		t.Error("bad alignment parsed")
+	}
+	for _, src := range []string{
+		".row {\n  .cell {\n    x\n  }\n}\n",
+		".table {\n  .cell {\n    x\n  }\n}\n",
+		".table {\n  .row {\n    .row {\n    }\n  }\n}\n",
+	} {
+		_, err := Parse(strings.NewReader(src))
+		if l, ok := err.(ErrorList); !ok || l[0].Code != ErrMisplaced {
+			t.Errorf("Parse(%q) = %v, want a misplaced error", src, err)
+		}
+	}
+}
+
+func TestOpenItems(t *testing.T) {
+	n, err := Parse(strings.NewReader(".listc {\n  [ ] a\n  [x] b\n  .item [] {\n    .listc {\n      [ ] c\n    }\n  }\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := OpenItems(n)
+	if len(open) != 3 {
+		t.Fatalf("got %d open items, want 3", len(open))
+	}
+	if got := open[0].Children[0].TextInfo.Text; got != "a" {
+		t.Errorf("first open item is %q, want %q", got, "a")
+	}
+	if got := open[2].Start.Column; got != 7 {
+		t.Errorf("nested item starts at column %d, want 7", got)
+	}
+
+	if _, err := Parse(strings.NewReader(".listc {\n  .item [y] {\n  }\n}\n")); err == nil {
+		t.Error("bad checkbox parsed")
+	}
+}
@@ -0,0 +1,177 @@
+package note
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kinds are the names of the things refs may refer to.
+var kinds = map[NodeType]string{
+	NodeSection:     "Section",
+	NodeTheorem:     "Theorem",
+	NodeCorollary:   "Corollary",
+	NodeProposition: "Proposition",
+	NodeDefinition:  "Definition",
+	NodeExample:     "Example",
+	NodeProblem:     "Problem",
+	NodeAlgorithm:   "Algorithm",
+	NodeEquation:    "Equation",
+	NodeImage:       "Figure",
+}
+
+// commands are the DSL commands that make the nodes with symbols.
+var commands = map[NodeType]string{
+	NodeSection:     "sec",
+	NodeTheorem:     "thm",
+	NodeCorollary:   "cor",
+	NodeProposition: "prop",
+	NodeDefinition:  "def",
+	NodeExample:     "ex",
+	NodeProblem:     "prob",
+	NodeAlgorithm:   "alg",
+	NodeEquation:    "eq",
+	NodeImage:       "img",
+}
+
+// Numbering is the numbers of a document's sections, environments,
+// equations and figures, and where each of its symbols is defined.
+//
+// Sections are numbered by depth, as in "2.1". Theorems, definitions and
+// the other environments share a count within each top level section, as
+// in "2.3". Equations and figures are counted through the document.
+type Numbering struct {
+	Numbers map[*Node]string
+	Symbols map[string]*Node
+}
+
+// Name is how a ref to n reads, as in "Theorem 2.3" or "Equation (4)".
+func (nb *Numbering) Name(n *Node) string {
+	if n.Type == NodeEquation {
+		return fmt.Sprintf("%s (%s)", kinds[n.Type], nb.Numbers[n])
+	}
+	return kinds[n.Type] + " " + nb.Numbers[n]
+}
+
+// Lookup is the name of what symbol refers to, if it is defined.
+func (nb *Numbering) Lookup(symbol string) (string, bool) {
+	n, ok := nb.Symbols[symbol]
+	if !ok {
+		return "", false
+	}
+	return nb.Name(n), true
+}
+
+type numberState struct {
+	*Numbering
+	sections []int // counts of sections at each depth entered so far
+	depth    int   // of sections
+	envs     int
+	eqs      int
+	figs     int
+	errs     ErrorList
+}
+
+func (s *numberState) pre(n *Node) {
+	if _, ok := kinds[n.Type]; !ok {
+		return
+	}
+
+	switch n.Type {
+	case NodeSection:
+		if s.depth == 0 {
+			s.envs = 0 // environments are numbered within top level sections
+		}
+		if len(s.sections) <= s.depth {
+			s.sections = append(s.sections, 0)
+		}
+		s.sections = s.sections[:s.depth+1] // deeper counts start over
+		s.sections[s.depth]++
+		s.depth++
+
+		parts := make([]string, s.depth)
+		for i, c := range s.sections {
+			parts[i] = fmt.Sprint(c)
+		}
+		s.Numbers[n] = strings.Join(parts, ".")
+	case NodeEquation:
+		s.eqs++
+		s.Numbers[n] = fmt.Sprint(s.eqs)
+	case NodeImage:
+		s.figs++
+		s.Numbers[n] = fmt.Sprint(s.figs)
+	default:
+		s.envs++
+		if len(s.sections) > 0 {
+			s.Numbers[n] = fmt.Sprintf("%d.%d", s.sections[0], s.envs)
+		} else {
+			s.Numbers[n] = fmt.Sprint(s.envs)
+		}
+	}
+
+	sym, _, _ := symbol(n)
+	if sym == "" {
+		return
+	}
+	if first, ok := s.Symbols[sym]; ok {
+		s.errorAt(n, ErrDuplicateSymbol, "symbol %q already defined by %s at %s", sym, s.Name(first), first.Start)
+		return
+	}
+	s.Symbols[sym] = n
+}
+
+func (s *numberState) post(n *Node) {
+	if n.Type == NodeSection {
+		s.depth--
+	}
+}
+
+func (s *numberState) errorAt(n *Node, code ErrorCode, format string, a ...interface{}) {
+	cmd, ok := commands[n.Type]
+	if !ok {
+		cmd = string(n.Type)
+	}
+	s.errs = append(s.errs, &ParseError{
+		Line:    n.Start.Line,
+		Column:  n.Start.Column,
+		Offset:  n.Start.Offset,
+		Command: cmd,
+		Code:    code,
+		Msg:     fmt.Sprintf(format, a...),
+	})
+}
+
+// number numbers n, without touching it. The symbols defined twice are
+// reported; the first definition is the one kept.
+func number(n *Node) (*Numbering, ErrorList) {
+	s := &numberState{
+		Numbering: &Numbering{
+			Numbers: make(map[*Node]string),
+			Symbols: make(map[string]*Node),
+		},
+	}
+	n.Walk(s.pre, s.post)
+	return s.Numbering, s.errs
+}
+
+// Resolve numbers n and fills in the text of each of its refs with the
+// name of what it refers to, as in "Theorem 2.3". It reports symbols that
+// are defined more than once and refs to symbols that aren't defined;
+// the errors carry the positions of the nodes involved, if they were
+// parsed.
+func Resolve(n *Node) (*Numbering, ErrorList) {
+	nb, errs := number(n)
+	s := &numberState{Numbering: nb, errs: errs}
+	n.Walk(func(n *Node) {
+		if n.Type != NodeRef {
+			return
+		}
+		name, ok := nb.Lookup(n.RefInfo.Ref)
+		if !ok {
+			n.RefInfo.Text = ""
+			s.errorAt(n, ErrUndefinedSymbol, "undefined symbol %q", n.RefInfo.Ref)
+			return
+		}
+		n.RefInfo.Text = name
+	}, func(n *Node) {})
+	return nb, s.errs
+}
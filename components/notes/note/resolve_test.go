@@ -0,0 +1,82 @@
+package note
+
+import (
+	"strings"
+	"testing"
+)
+
+const resolveSrc = `.doc notes Notes article
+.sec intro Introduction {
+  .def d1 _ {
+    x
+  }
+  .sec more More {
+    .thm t1 _ {
+      .ref d1
+    }
+  }
+}
+.sec main Main {
+  .eq e1 {
+    x = 1
+  }
+  .thm t2 _ {
+    .ref t1
+    .ref e1
+    .ref more
+    .ref nowhere
+  }
+  .cor d1 _ {
+  }
+}
+`
+
+func TestResolve(t *testing.T) {
+	n, err := Parse(strings.NewReader(resolveSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, errs := Resolve(n)
+
+	for sym, want := range map[string]string{
+		"intro": "Section 1",
+		"more":  "Section 1.1",
+		"main":  "Section 2",
+		"d1":    "Definition 1.1",
+		"t1":    "Theorem 1.2",
+		"e1":    "Equation (1)",
+		"t2":    "Theorem 2.1",
+	} {
+		if got, ok := nb.Lookup(sym); !ok || got != want {
+			t.Errorf("Lookup(%q) = %q, %t; want %q", sym, got, ok, want)
+		}
+	}
+
+	var refs []string
+	n.Walk(func(n *Node) {
+		if n.Type == NodeRef {
+			refs = append(refs, n.RefInfo.Text)
+		}
+	}, func(*Node) {})
+	if got, want := strings.Join(refs, ","), "Definition 1.1,Theorem 1.2,Equation (1),Section 1.1,"; got != want {
+		t.Errorf("ref texts = %q, want %q", got, want)
+	}
+
+	m, err := Parse(strings.NewReader(resolveSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(n, m) {
+		t.Error("Equal(Resolve(n), n) = false, want true")
+	}
+
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), errs)
+	}
+	if e := errs[0]; e.Code != ErrDuplicateSymbol || e.Command != "cor" || e.Line != 22 {
+		t.Errorf("errs[0] = %v (%s), want a duplicate .cor on line 22", e, e.Code)
+	}
+	if e := errs[1]; e.Code != ErrUndefinedSymbol || e.Command != "ref" || e.Line != 20 {
+		t.Errorf("errs[1] = %v (%s), want an undefined .ref on line 20", e, e.Code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/nlandolfi/elos/web-client/components/notes/note"
 	"github.com/spinsrv/browser"
@@ -23,6 +24,7 @@ type State struct {
 	Selected  *note.Node `json:"-"`
 
 	Status string
+	Errors note.ErrorList `json:"-"`
 
 	Selection dom.Selection `json:"-"`
 }
@@ -30,15 +32,15 @@ type State struct {
 func (s *State) Handle(e browser.Event) {
 	switch e := e.(type) {
 	case EventKey:
+		// may be mid typing, so keep the preview going regardless
 		b := bytes.NewBufferString(s.Raw)
-		n, err := note.Parse(b)
-		if err != nil {
-			s.Status = err.Error()
-			return // don't worry, may be mid typing
-			//			log.Fatal(err)
-		} else {
-			s.Status = ""
-			s.Root = n
+		n, errs := note.ParseTolerant(b)
+		_, rerrs := note.Resolve(n)
+		s.setErrors(append(errs, rerrs...).Err())
+		s.Root = n
+		if s.Selected != nil {
+			// follow the selection into the new tree, by where it started
+			s.Selected = n.NodeAt(s.Selected.Start.Offset)
 		}
 		/*
 			var bb bytes.Buffer
@@ -51,11 +53,12 @@ func (s *State) Handle(e browser.Event) {
 		b := bytes.NewBufferString(s.Raw)
 		n, err := note.Parse(b)
 		if err != nil {
-			s.Status = err.Error()
+			s.setErrors(err)
 			return // don't worry, may be mid typing
 			//			log.Fatal(err)
 		} else {
-			s.Status = ""
+			_, rerrs := note.Resolve(n)
+			s.setErrors(rerrs.Err())
 			s.Root = n
 		}
 		var bb bytes.Buffer
@@ -75,6 +78,8 @@ func (s *State) Handle(e browser.Event) {
 		s.Raw = b.String()
 	case EventReset:
 		s.Root = note.Document("", "", note.DocumentArticle)
+	case EventToggleCheck:
+		s.toggleCheck(e.Node)
 	case EventSelectNode:
 		if s.Selected == e.Node {
 			s.Selected = nil
@@ -84,10 +89,63 @@ func (s *State) Handle(e browser.Event) {
 	}
 }
 
+// toggleCheck checks or unchecks item. Its box is changed where it is in
+// the raw text, so that the rest of the text is left as it was typed.
+func (s *State) toggleCheck(item *note.Node) {
+	if item.ItemInfo == nil {
+		return
+	}
+	box := "[x]"
+	if item.ItemInfo.Checked {
+		box = "[ ]"
+	}
+
+	if item.End.Offset > item.Start.Offset && item.End.Offset <= len(s.Raw) {
+		line := s.Raw[item.Start.Offset:item.End.Offset]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.IndexByte(line, '['); i >= 0 {
+			if j := strings.IndexByte(line[i:], ']'); j >= 0 {
+				at := item.Start.Offset + i
+				s.Raw = s.Raw[:at] + box + s.Raw[at+j+1:]
+				s.Handle(EventKey{})
+				return
+			}
+		}
+	}
+
+	// not from the raw text, so write the whole tree out again
+	item.ItemInfo.Checked = !item.ItemInfo.Checked
+	var b bytes.Buffer
+	note.Render(&b, s.Root)
+	s.Raw = b.String()
+}
+
+func (s *State) setErrors(err error) {
+	s.Errors = nil
+	s.Status = ""
+	if err == nil {
+		return
+	}
+	el, ok := err.(note.ErrorList)
+	if !ok {
+		s.Status = err.Error()
+		return
+	}
+	s.Errors = el
+	if len(el) == 1 {
+		s.Status = "1 problem:"
+	} else {
+		s.Status = fmt.Sprintf("%d problems:", len(el))
+	}
+}
+
 type EventToggleRaw struct{}
 type EventAppendNode struct{ Node note.Node }
 type EventReset struct{}
 type EventSelectNode struct{ Node *note.Node }
+type EventToggleCheck struct{ Node *note.Node }
 type EventToggleDebugging struct{ Node *note.Node }
 type EventKey struct{}
 type EventFormat struct{}
@@ -103,6 +161,12 @@ func View(s *State) *browser.Node {
 		),
 		s.Theme.Textf("AchorNode: %+v", s.Selection.AnchorNode()),
 		s.Theme.Text(s.Status),
+		ui.OnlyIf(len(s.Errors) > 0,
+			func() *browser.Node { return s.errorsView() },
+		),
+		ui.OnlyIf(s.Selected != nil,
+			func() *browser.Node { return s.sourceView(s.Selected) },
+		),
 		ui.HStack(
 			ui.VStack(
 				ui.HStack(
@@ -116,8 +180,12 @@ func View(s *State) *browser.Node {
 						OnClickDispatch(EventAppendNode{Node: *note.OrderedList(nil)}),
 					s.Theme.Button("UL").
 						OnClickDispatch(EventAppendNode{Node: *note.UnorderedList(nil)}),
+					s.Theme.Button("CL").
+						OnClickDispatch(EventAppendNode{Node: *note.CheckList(nil)}),
 					s.Theme.Button("LI").
 						OnClickDispatch(EventAppendNode{Node: *note.ListItem()}),
+					s.Theme.Button("Table").
+						OnClickDispatch(EventAppendNode{Node: *note.Table("", note.TableRow(true, []string{"", ""}))}),
 				),
 				s.Theme.Card(s.render(s.Root)).WidthPX(500).
 					OnClickDispatch(nil),
@@ -149,6 +217,37 @@ func View(s *State) *browser.Node {
 	})
 }
 
+// errorsView lists each parse error along with the raw line it points at.
+func (s *State) errorsView() *browser.Node {
+	lines := strings.Split(s.Raw, "\n")
+	views := make([]*browser.Node, len(s.Errors))
+	for i, e := range s.Errors {
+		var src string
+		if e.Line-1 < len(lines) {
+			src = lines[e.Line-1]
+		}
+		views[i] = ui.VStack(
+			s.Theme.Text(e.Error()).Color("red"), // TODO use theme
+			s.Theme.Textf("%4d | %s", e.Line, strings.ReplaceAll(src, " ", "\u00a0")).FontFamily("monospace").FontSizePX(12),
+			// non-breaking spaces, so the caret lines up under the column
+			s.Theme.Textf("%s| %s^", strings.Repeat("\u00a0", 5), strings.Repeat("\u00a0", e.Column-1)).FontFamily("monospace").FontSizePX(12),
+		).MarginBottomPX(5)
+	}
+	return ui.VStack(views...)
+}
+
+// sourceView shows the raw text the node was parsed from.
+func (s *State) sourceView(n *note.Node) *browser.Node {
+	var src string
+	if n.Start.Offset <= n.End.Offset && n.End.Offset <= len(s.Raw) {
+		src = s.Raw[n.Start.Offset:n.End.Offset]
+	}
+	return ui.VStack(
+		s.Theme.Textf("%s, from %s to %s:", n.Type, n.Start, n.End),
+		s.Theme.TextArea(&src).FontFamily("monospace"),
+	)
+}
+
 func (s *State) block(n *browser.Node, nn *note.Node, selected bool) *browser.Node {
 	var color = "gray"
 	if selected {
@@ -197,6 +296,11 @@ func (s *State) render(n *note.Node) *browser.Node {
 
 	var stack []*browser.Node = []*browser.Node{root}
 
+	// how the cells of the tables are to be shown, worked out as each
+	// table is reached
+	aligns := make(map[*note.Node]note.Alignment)
+	headers := make(map[*note.Node]bool)
+
 	n.Walk(func(n *note.Node) {
 
 		//		log.Printf("Walk n.Type = %s; entering=%t: stack: %v", n.Type, true, stack)
@@ -227,10 +331,9 @@ func (s *State) render(n *note.Node) *browser.Node {
 				nextNode = s.Theme.H1()
 			case 2:
 				nextNode = s.Theme.H2()
-			case 3:
-				nextNode = s.Theme.H3()
 			default:
-				log.Fatalf("unhandled header level: %d", n.HeaderInfo.Level)
+				// includes the zero level of a half typed ".header"
+				nextNode = s.Theme.H3()
 			}
 		case note.NodeImage:
 			nextNode = &browser.Node{
@@ -263,6 +366,25 @@ func (s *State) render(n *note.Node) *browser.Node {
 					},
 				},
 			}
+		case note.NodeRef:
+			nextNode = &browser.Node{
+				Type:     html.ElementNode,
+				DataAtom: atom.A,
+				Attr: []*html.Attribute{
+					&html.Attribute{
+						Key: "href",
+						Val: "#" + n.RefInfo.Ref,
+					},
+				},
+			}
+			if len(n.Children) == 0 {
+				if n.RefInfo.Text != "" {
+					nextNode.Children = []*browser.Node{s.Theme.Text(n.RefInfo.Text)}
+				} else {
+					// Resolve found nothing by that symbol
+					nextNode.Children = []*browser.Node{s.Theme.Textf("%s??", n.RefInfo.Ref).Color("red")}
+				}
+			}
 		case note.NodeList:
 			switch n.ListInfo.Type {
 			case note.ListOrdered:
@@ -270,7 +392,7 @@ func (s *State) render(n *note.Node) *browser.Node {
 					Type:     html.ElementNode,
 					DataAtom: atom.Ol,
 				}
-			case note.ListUnordered:
+			case note.ListUnordered, note.ListCheck:
 				nextNode = &browser.Node{
 					Type:     html.ElementNode,
 					DataAtom: atom.Ul,
@@ -283,10 +405,84 @@ func (s *State) render(n *note.Node) *browser.Node {
 				Type:     html.ElementNode,
 				DataAtom: atom.Li,
 			}
+			if n.ItemInfo != nil {
+				box := &browser.Node{
+					Type:     html.ElementNode,
+					DataAtom: atom.Input,
+					Attr: []*html.Attribute{
+						&html.Attribute{
+							Key: "type",
+							Val: "checkbox",
+						},
+					},
+				}
+				if n.ItemInfo.Checked {
+					box.Attr = append(box.Attr, &html.Attribute{
+						Key: "checked",
+						Val: "checked",
+					})
+				}
+				nextNode.Children = append(nextNode.Children, box.OnClick(func(e dom.Event) {
+					e.StopPropagation()
+					go browser.Dispatch(EventToggleCheck{n})
+				}))
+			}
+		case note.NodeTable:
+			for _, r := range n.Children {
+				if r.Type != note.NodeTableRow {
+					continue
+				}
+				for i, c := range r.Children {
+					aligns[c] = n.TableInfo.Alignment(i)
+					headers[c] = r.TableRowInfo.Header
+				}
+			}
+			nextNode = &browser.Node{
+				Type:     html.ElementNode,
+				DataAtom: atom.Table,
+			}
+		case note.NodeTableRow:
+			nextNode = &browser.Node{
+				Type:     html.ElementNode,
+				DataAtom: atom.Tr,
+			}
+		case note.NodeTableCell:
+			nextNode = &browser.Node{
+				Type:     html.ElementNode,
+				DataAtom: atom.Td,
+			}
+			if headers[n] {
+				nextNode.DataAtom = atom.Th
+			}
+			if a, ok := aligns[n]; ok {
+				nextNode.Attr = append(nextNode.Attr, &html.Attribute{
+					Key: "align",
+					Val: string(a),
+				})
+			}
 		case note.NodeText:
 			nextNode = s.Theme.Text(n.TextInfo.Text)
+		case note.NodeError:
+			nextNode = ui.VStack(
+				s.Theme.Text(n.ErrorInfo.Source).
+					FontFamily("monospace").
+					Color("red"), // TODO use theme
+			)
 		default:
-			log.Fatalf("unhandled node type: %s", n.Type)
+			// the preview is rebuilt on every key, so don't bring down
+			// the client over a node we can't show yet.
+			nextNode = s.Theme.Text(n.Debug()).FontFamily("monospace").Color("gray")
+		}
+
+		// nodes that came from the source can be clicked to show it
+		if n.End.Offset > n.Start.Offset {
+			nextNode = nextNode.OnClick(func(e dom.Event) {
+				e.StopPropagation()
+				go browser.Dispatch(EventSelectNode{n})
+			})
+		}
+		if n == s.Selected {
+			nextNode = nextNode.Background("lightyellow") // TODO use theme
 		}
 
 		parent.Children = append(parent.Children, nextNode)
@@ -321,8 +517,13 @@ func isContainer(n *note.Node) bool {
 		note.NodeList,
 		note.NodeListItem,
 		note.NodeItalics,
-		note.NodeBold, note.NodeLink: // incomplete
+		note.NodeBold, note.NodeLink,
+		note.NodeTable, note.NodeTableRow, note.NodeTableCell: // incomplete
 		return true
+	case note.NodeRef:
+		return len(n.Children) > 0
+	case note.NodeError:
+		return n.ErrorInfo.Block
 	default:
 		return false
 	}
@@ -3,6 +3,7 @@ package notes
 import (
 	"bytes"
 	"fmt"
+	"strings"
 
 	"github.com/nlandolfi/elos/web-client/components/notes/canvas"
 	"github.com/nlandolfi/elos/web-client/components/notes/manager"
@@ -76,6 +77,8 @@ var items = []*selector.Item{
 }
 
 type EventReloadNotes struct{}
+type EventMarkdownToPrototype struct{}
+type EventPrototypeToMarkdown struct{}
 
 func (s *State) Handle(e browser.Event) {
 	s.SelectorState.Handle(e)
@@ -85,6 +88,22 @@ func (s *State) Handle(e browser.Event) {
 	switch e.(type) {
 	case EventReloadNotes:
 		go s.reloadNotes()
+	case EventMarkdownToPrototype:
+		n := markdown.Import([]byte(s.MarkdownState.Markdown))
+		var b bytes.Buffer
+		note.Render(&b, n)
+		s.PrototypeState.Root = n
+		s.PrototypeState.Raw = b.String()
+		s.PrototypeState.Selected = nil
+		s.SelectorState.SelectedKey = "prototype"
+	case EventPrototypeToMarkdown:
+		md, err := markdown.Export(s.PrototypeState.Root)
+		if err != nil {
+			s.Status = err.Error()
+			return
+		}
+		s.MarkdownState.Markdown = strings.Replace(string(md), "\t", " ", -1)
+		s.SelectorState.SelectedKey = "markdown"
 	}
 }
 
@@ -94,11 +113,17 @@ func View(s *State) *browser.Node {
 	case "main", "":
 		view = main(s)
 	case "markdown":
-		view = markdown.View(&s.MarkdownState)
+		view = ui.VStack(
+			s.Theme.Button("To Prototype").OnClickDispatch(EventMarkdownToPrototype{}),
+			markdown.View(&s.MarkdownState),
+		)
 	case "manager":
 		view = manager.View(&s.ManagerState)
 	case "prototype":
-		view = prototype.View(&s.PrototypeState)
+		view = ui.VStack(
+			s.Theme.Button("To Markdown").OnClickDispatch(EventPrototypeToMarkdown{}),
+			prototype.View(&s.PrototypeState),
+		)
 	case "canvas":
 		view = canvas.View(&s.CanvasState)
 	default: